@@ -0,0 +1,77 @@
+package soap
+
+// Array is a list of values encoded as SOAP-ENC:Array with item elements.
+type Array []interface{}
+
+// A Builder builds a Struct element child by child, e.g.
+//
+//	e := soap.Build("Order").
+//		Child("Id", 42).
+//		Child("Lines", soap.Array{line1, line2}).
+//		Child("Customer", soap.Build("").Child("Name", "Bob")).
+//		Element()
+type Builder struct {
+	p   *Profile
+	e   *Element
+	err error
+}
+
+// Build returns a Builder of an element of given name that uses conventions
+// of DefaultProfile.
+func Build(name string) *Builder {
+	return DefaultProfile.Build(name)
+}
+
+// Build works like package level Build function but uses conventions of p.
+func (p *Profile) Build(name string) *Builder {
+	e := newElement()
+	e.XMLName.Local = name
+	e.Type = p.encType("Struct")
+	return &Builder{p: p, e: e}
+}
+
+// Child adds a child element of given name made from a, as MakeElement does.
+// a can also be a *Builder, whose element is added renamed to name, or an
+// *Element, which is added as a copy renamed to name (nil with xsi:nil).
+func (b *Builder) Child(name string, a interface{}) *Builder {
+	if b.err != nil {
+		return b
+	}
+	var c *Element
+	switch a := a.(type) {
+	case *Builder:
+		if a.err != nil {
+			b.err = a.err
+			return b
+		}
+		c = a.e
+	case *Element:
+		if a == nil {
+			c = newElement()
+			c.Nil = true
+		} else {
+			c = a.Clone()
+		}
+	default:
+		c, b.err = b.p.Marshal(name, a)
+		if b.err != nil {
+			return b
+		}
+	}
+	c.XMLName.Local = name
+	b.e.AddChild(c)
+	return b
+}
+
+// Err returns the first error that occurred while adding children.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// Element returns the built element. It panics if Err returns an error.
+func (b *Builder) Element() *Element {
+	if b.err != nil {
+		panic(b.err)
+	}
+	return b.e
+}
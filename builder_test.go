@@ -0,0 +1,29 @@
+package soap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuilderElementChild(t *testing.T) {
+	src := Build("Src").Child("A", 1).Element()
+	var none *Element
+	e := Build("R").Child("X", src).Child("Y", src).Child("Z", none).Element()
+	if src.XMLName.Local != "Src" {
+		t.Errorf("source renamed to %s", src.XMLName.Local)
+	}
+	cs := e.Children
+	if len(cs) != 3 || cs[0] == src || cs[1] == src || cs[0] == cs[1] {
+		t.Fatalf("children aren't copies: %v", cs)
+	}
+	if cs[0].XMLName.Local != "X" || cs[1].XMLName.Local != "Y" ||
+		cs[1].child("A") == nil {
+		t.Errorf("bad copies: %s, %s", cs[0].XMLName.Local, cs[1].XMLName.Local)
+	}
+	if !cs[2].Nil || cs[2].XMLName.Local != "Z" {
+		t.Errorf("nil element isn't written as xsi:nil: %+v", cs[2])
+	}
+	if b := new(Encoder).AppendElement(nil, cs[2]); !strings.Contains(string(b), `:nil="true"`) {
+		t.Errorf("nil element written as %s", b)
+	}
+}
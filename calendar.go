@@ -0,0 +1,243 @@
+package soap
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Partial dates of XML Schema. They are decoded with an optional zone (Z or
+// an offset), which is ignored, and written without zone.
+type (
+	// GYear is a value of xsd:gYear type, e.g. 2024 written as "2024".
+	GYear int
+
+	// GMonth is a value of xsd:gMonth type, e.g. December written as
+	// "--12".
+	GMonth time.Month
+
+	// GDay is a value of xsd:gDay type, a day of month, e.g. 31 written as
+	// "---31".
+	GDay int
+
+	// GMonthDay is a value of xsd:gMonthDay type, e.g. December 31 written
+	// as "--12-31".
+	GMonthDay struct {
+		Month time.Month
+		Day   int
+	}
+)
+
+func (y GYear) String() string {
+	n := int(y)
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	s := strconv.Itoa(n)
+	for len(s) < 4 {
+		s = "0" + s
+	}
+	return sign + s
+}
+
+func (m GMonth) String() string {
+	return "--" + twoDigits(int(m))
+}
+
+func (d GDay) String() string {
+	return "---" + twoDigits(int(d))
+}
+
+func (md GMonthDay) String() string {
+	return "--" + twoDigits(int(md.Month)) + "-" + twoDigits(md.Day)
+}
+
+func twoDigits(n int) string {
+	if n >= 0 && n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// calendarTypes maps partial date types to names of their XSD types.
+var calendarTypes = map[reflect.Type]string{
+	reflect.TypeOf(GYear(0)):    "gYear",
+	reflect.TypeOf(GMonth(0)):   "gMonth",
+	reflect.TypeOf(GDay(0)):     "gDay",
+	reflect.TypeOf(GMonthDay{}): "gMonthDay",
+}
+
+// setCalendar sets type and text of e to v of a partial date type typ.
+func (p *Profile) setCalendar(e *Element, typ string, v interface{}) {
+	e.Type = p.xsdType(typ)
+	e.Text = v.(interface{ String() string }).String()
+}
+
+var errBadCalendar = errors.New("soap: malformed partial date")
+
+// parseCalendar parses s as a value of partial date type typ.
+func parseCalendar(typ, s string) (interface{}, error) {
+	s = trimZone(strings.TrimSpace(s))
+	switch typ {
+	case "gYear":
+		digits := strings.TrimPrefix(s, "-")
+		if len(digits) < 4 || (len(digits) > 4 && digits[0] == '0') {
+			return nil, errBadCalendar
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil || n == 0 || strings.ContainsAny(digits, "+-") {
+			return nil, errBadCalendar
+		}
+		if len(digits) != len(s) {
+			n = -n
+		}
+		return GYear(n), nil
+
+	case "gMonth":
+		if len(s) != 4 || s[:2] != "--" {
+			return nil, errBadCalendar
+		}
+		m, ok := parseTwoDigits(s[2:], 12)
+		if !ok {
+			return nil, errBadCalendar
+		}
+		return GMonth(m), nil
+
+	case "gDay":
+		if len(s) != 5 || s[:3] != "---" {
+			return nil, errBadCalendar
+		}
+		d, ok := parseTwoDigits(s[3:], 31)
+		if !ok {
+			return nil, errBadCalendar
+		}
+		return GDay(d), nil
+
+	case "gMonthDay":
+		if len(s) != 7 || s[:2] != "--" || s[4] != '-' {
+			return nil, errBadCalendar
+		}
+		m, ok1 := parseTwoDigits(s[2:4], 12)
+		d, ok2 := parseTwoDigits(s[5:], 31)
+		if !ok1 || !ok2 {
+			return nil, errBadCalendar
+		}
+		// February 29 is valid, so any leap year will do.
+		if time.Date(2000, time.Month(m), d, 0, 0, 0, 0, time.UTC).Day() != d {
+			return nil, errBadCalendar
+		}
+		return GMonthDay{time.Month(m), d}, nil
+	}
+	return nil, nil
+}
+
+// trimZone removes the zone (Z, +hh:mm or -hh:mm) from the end of s.
+func trimZone(s string) string {
+	if strings.HasSuffix(s, "Z") {
+		return s[:len(s)-1]
+	}
+	if n := len(s) - 6; n > 0 && (s[n] == '+' || s[n] == '-') && s[n+3] == ':' {
+		return s[:n]
+	}
+	return s
+}
+
+// parseTwoDigits parses s of two digits as a number from 1 to max.
+func parseTwoDigits(s string, max int) (int, bool) {
+	if len(s) != 2 || s[0] < '0' || s[0] > '9' || s[1] < '0' || s[1] > '9' {
+		return 0, false
+	}
+	n := int(s[0]-'0')*10 + int(s[1]-'0')
+	return n, n >= 1 && n <= max
+}
+
+// calendar returns the value of e as partial date of type typ. If strict is
+// set the type of e must be typ.
+func (e *Element) calendar(typ string, strict bool) (interface{}, error) {
+	if strict && skipNS(e.Type) != typ {
+		return nil, e.typeError(typ)
+	}
+	if e.hasChildren() {
+		return nil, e.badValue(typ, nil)
+	}
+	if e.Nil {
+		return nil, nil
+	}
+	v, err := parseCalendar(typ, e.Text)
+	if err != nil {
+		return nil, e.badValue(typ, err)
+	}
+	return v, nil
+}
+
+// GYear returns the value of e of xsd:gYear type.
+func (e *Element) GYear() (GYear, error) {
+	v, err := e.calendar("gYear", true)
+	y, _ := v.(GYear)
+	return y, err
+}
+
+// AsGYear parses the text of e as GYear regardless of the type of e.
+func (e *Element) AsGYear() (GYear, error) {
+	v, err := e.calendar("gYear", false)
+	y, _ := v.(GYear)
+	return y, err
+}
+
+// GMonth returns the value of e of xsd:gMonth type.
+func (e *Element) GMonth() (GMonth, error) {
+	v, err := e.calendar("gMonth", true)
+	m, _ := v.(GMonth)
+	return m, err
+}
+
+// AsGMonth parses the text of e as GMonth regardless of the type of e.
+func (e *Element) AsGMonth() (GMonth, error) {
+	v, err := e.calendar("gMonth", false)
+	m, _ := v.(GMonth)
+	return m, err
+}
+
+// GDay returns the value of e of xsd:gDay type.
+func (e *Element) GDay() (GDay, error) {
+	v, err := e.calendar("gDay", true)
+	d, _ := v.(GDay)
+	return d, err
+}
+
+// AsGDay parses the text of e as GDay regardless of the type of e.
+func (e *Element) AsGDay() (GDay, error) {
+	v, err := e.calendar("gDay", false)
+	d, _ := v.(GDay)
+	return d, err
+}
+
+// GMonthDay returns the value of e of xsd:gMonthDay type.
+func (e *Element) GMonthDay() (GMonthDay, error) {
+	v, err := e.calendar("gMonthDay", true)
+	md, _ := v.(GMonthDay)
+	return md, err
+}
+
+// AsGMonthDay parses the text of e as GMonthDay regardless of the type of e.
+func (e *Element) AsGMonthDay() (GMonthDay, error) {
+	v, err := e.calendar("gMonthDay", false)
+	md, _ := v.(GMonthDay)
+	return md, err
+}
+
+// calendarLoader returns the loadFunc of fields of partial date type typ.
+func calendarLoader(typ string) loadFunc {
+	return func(item *Element, v reflect.Value, o *loadOpts) error {
+		x, err := item.calendar(typ, o.mode == Strict)
+		if x == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(x))
+		}
+		return err
+	}
+}
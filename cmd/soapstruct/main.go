@@ -0,0 +1,67 @@
+// Soapstruct reads a captured SOAP response (or any XML document) and prints
+// Go struct definitions that soap.Element.LoadStruct can load it into.
+//
+// Usage:
+//
+//	soapstruct [-pkg name] [file]
+//
+// If the document is a SOAP envelope, the first element of its Body is used.
+// Without file argument the document is read from standard input.
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ziutek/soap"
+)
+
+func die(err error) {
+	fmt.Fprintln(os.Stderr, "soapstruct:", err)
+	os.Exit(1)
+}
+
+func bodyContent(e *soap.Element) *soap.Element {
+	if e.XMLName.Local != "Envelope" {
+		return e
+	}
+	for _, c := range e.Children {
+		if c.XMLName.Local == "Body" && len(c.Children) != 0 {
+			return c.Children[0]
+		}
+	}
+	return e
+}
+
+func main() {
+	pkg := flag.String("pkg", "main", "package name of generated source")
+	flag.Parse()
+
+	var r io.Reader = os.Stdin
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			die(err)
+		}
+		defer f.Close()
+		r = f
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	e := new(soap.Element)
+	if err := xml.NewDecoder(r).Decode(e); err != nil {
+		die(err)
+	}
+	src, err := soap.StructSource(*pkg, bodyContent(e))
+	if err != nil {
+		die(err)
+	}
+	os.Stdout.Write(src)
+}
@@ -0,0 +1,655 @@
+package soap
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Codec encodes and loads values of struct type T. Closures handling every
+// field are built once by CompileCodec, so Encode and Load don't inspect the
+// type (nor box scalar fields) on each call. Fields of types other than
+// scalars, time.Time and nested structs fall back to MakeElement conventions.
+type Codec[T any] struct {
+	// Profile used by Encode. If nil, DefaultProfile is used.
+	Profile *Profile
+
+	// Decoder used by Load. If nil, DefaultDecoder is used.
+	Decoder *Decoder
+
+	sc *structCodec
+}
+
+// CompileCodec returns a Codec for T. T must be a struct type.
+func CompileCodec[T any]() (*Codec[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("soap: CompileCodec of non-struct type " + t.String())
+	}
+	return &Codec[T]{sc: compileStruct(t)}, nil
+}
+
+// Encode works like Marshal(name, v).
+func (c *Codec[T]) Encode(name string, v *T) (*Element, error) {
+	p := c.Profile
+	if p == nil {
+		p = DefaultProfile
+	}
+	e := newElement()
+	e.XMLName.Local = name
+	if v == nil {
+		e.Nil = true
+		return e, nil
+	}
+	if err := c.sc.encode(p, e, reflect.ValueOf(v).Elem(), 1); err != nil {
+		e.Release()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Load works like Decoder.Load(e, v, mode) of c.Decoder.
+func (c *Codec[T]) Load(e *Element, v *T, mode DecodeMode) error {
+	dec := c.Decoder
+	if dec == nil {
+		dec = DefaultDecoder
+	}
+	return c.sc.load(e, reflect.ValueOf(v).Elem(), dec.loadOpts(mode))
+}
+
+// loadOpts holds settings of a Load call.
+type loadOpts struct {
+	mode DecodeMode
+	loc  *time.Location // location of times without zone
+	utc  bool           // convert times to UTC
+
+	parts          func(contentID string) (io.Reader, error) // see Decoder.Parts
+	lenientNumbers bool                                      // see Decoder.LenientNumbers
+}
+
+type (
+	encodeFunc func(p *Profile, e *Element, v reflect.Value, depth int) error
+	loadFunc   func(item *Element, v reflect.Value, o *loadOpts) error
+)
+
+// fieldCodec is a field with closures that encode and load it.
+type fieldCodec struct {
+	field
+	encode encodeFunc
+	load   loadFunc
+}
+
+type structCodec struct {
+	fields []fieldCodec
+	fs     []field // fields without closures, for checkChoice
+	choice bool    // some fields have the choice option
+}
+
+var codecCache sync.Map // map[reflect.Type]*structCodec
+
+// compileStruct returns the codec of struct type t, building it only once per
+// type. It is also used by LoadStruct.
+func compileStruct(t reflect.Type) *structCodec {
+	if c, ok := codecCache.Load(t); ok {
+		return c.(*structCodec)
+	}
+	fs := cachedFields(t)
+	c := &structCodec{fields: make([]fieldCodec, len(fs)), fs: fs}
+	for i, f := range fs {
+		c.fields[i] = fieldCodec{f, fieldEncoder(f.typ), fieldLoader(f.typ, f.opts)}
+		if f.digits != nil {
+			c.fields[i].load = f.digits.loader(f.typ)
+		}
+		c.choice = c.choice || f.choice != ""
+	}
+	cc, _ := codecCache.LoadOrStore(t, c)
+	return cc.(*structCodec)
+}
+
+func (c *structCodec) encode(p *Profile, e *Element, v reflect.Value, depth int) error {
+	if depth > p.maxDepth() {
+		return ErrTooDeep
+	}
+	e.Type = p.encType("Struct")
+	setStructName(e, v)
+	if c.choice {
+		err := checkChoice(c.fs, true, func(i int) bool {
+			return !isEmptyValue(v.Field(c.fs[i].index))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.fields {
+		f := &c.fields[i]
+		if f.in {
+			continue
+		}
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if f.check != nil {
+			if err := f.check(fv); err != nil {
+				return inPath(err, e.XMLName.Local)
+			}
+		}
+		if f.typ == elementsType {
+			e.Children = appendCopies(e.Children, f.name, fv.Interface().([]*Element))
+			continue
+		}
+		ce := newElement()
+		ce.XMLName = xml.Name{Space: e.XMLName.Space, Local: f.name}
+		if f.typ.Kind() == reflect.Interface {
+			ce.XMLName.Local = memberName(f.name, fv)
+		}
+		e.Children = append(e.Children, ce)
+		if f.digits != nil {
+			if err := f.digits.encode(p, ce, fv); err != nil {
+				return inPath(err, e.XMLName.Local)
+			}
+			continue
+		}
+		if err := f.encode(p, ce, fv, depth+1); err != nil {
+			return err
+		}
+		if f.union != nil {
+			p.unionType(ce, f.union)
+		}
+	}
+	return nil
+}
+
+func (c *structCodec) load(e *Element, s reflect.Value, o *loadOpts) error {
+	if xn := structXMLName(s.Type()); xn.index >= 0 {
+		s.Field(xn.index).Set(reflect.ValueOf(e.XMLName))
+	}
+	d := lookupSchemaElem(e.XMLName)
+	var present []bool // present[i] reports that the i-th field was found
+	if c.choice {
+		present = make([]bool, len(c.fields))
+	}
+	for i := range c.fields {
+		f := &c.fields[i]
+		fv := s.Field(f.index)
+		if f.typ == elementsType {
+			e.expand()
+			var els []*Element
+			for _, c := range e.Children {
+				if c != nil && c.XMLName.Local == f.name {
+					els = append(els, c.Clone())
+				}
+			}
+			fv.Set(reflect.ValueOf(els))
+			continue
+		}
+		item, err := e.Get(f.name)
+		if err != nil {
+			return err
+		}
+		if item == nil && f.typ.Kind() == reflect.Interface {
+			item = e.substituteOf(f.name)
+		}
+		if item == nil && f.choice != "" {
+			fv.Set(reflect.Zero(f.typ)) // alternative not chosen
+			continue
+		}
+		if present != nil {
+			present[i] = item != nil
+		}
+		if item == nil {
+			if o.mode == Lenient {
+				continue // absent elements leave fields unchanged
+			}
+			if k := f.typ.Kind(); (k == reflect.Ptr || k == reflect.Slice || k == reflect.Map) &&
+				f.omitEmpty {
+				continue // absent, unlike nil, leaves pointers, slices and maps unchanged
+			}
+			return noFieldError(f.name)
+		}
+		if o.mode == Strict && item.Type == "" {
+			if typ := d.field(f.name).typeOf(item); typ != "" {
+				item = item.withType("xsd:" + typ)
+			}
+		}
+		if err = f.load(item, fv, o); err != nil {
+			return inPath(err, e.XMLName.Local)
+		}
+		if f.check != nil {
+			if err = f.check(fv); err != nil {
+				return inPath(err, e.XMLName.Local)
+			}
+		}
+	}
+	if present != nil {
+		err := checkChoice(c.fs, o.mode != Lenient, func(i int) bool {
+			return present[i]
+		})
+		if err != nil {
+			return inPath(err, e.XMLName.Local)
+		}
+	}
+	return nil
+}
+
+// fieldEncoder returns the function that encodes values of type t.
+func fieldEncoder(t reflect.Type) encodeFunc {
+	if t == elementType {
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			if v.IsNil() {
+				e.Nil = true
+				return nil
+			}
+			e.setContent(v.Interface().(*Element))
+			return nil
+		}
+	}
+	if t == readerType {
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			r, _ := v.Interface().(io.Reader)
+			p.setStream(e, r)
+			return nil
+		}
+	}
+	if t == qnameType {
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			p.setQName(e, v.Interface().(QName))
+			return nil
+		}
+	}
+	if isUUIDType(t) {
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			p.setUUID(e, v)
+			return nil
+		}
+	}
+	if typ := calendarTypes[t]; typ != "" {
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			p.setCalendar(e, typ, v.Interface())
+			return nil
+		}
+	}
+	if t == timeType {
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			e.Type = p.xsdType("dateTime")
+			e.Text = p.formatTime(v.Interface().(time.Time))
+			return nil
+		}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			e.Type = p.xsdType("string")
+			e.Text = v.String()
+			return nil
+		}
+	case reflect.Bool:
+		return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+			e.Type = p.xsdType("boolean")
+			e.Text = p.boolLiteral(v.Bool())
+			return nil
+		}
+	case reflect.Int, reflect.Int64:
+		return intEncoder("long")
+	case reflect.Int32:
+		return intEncoder("int")
+	case reflect.Int16:
+		return intEncoder("short")
+	case reflect.Int8:
+		return intEncoder("byte")
+	case reflect.Uint, reflect.Uint64:
+		return uintEncoder("unsignedLong")
+	case reflect.Uint32:
+		return uintEncoder("unsignedInt")
+	case reflect.Uint16:
+		return uintEncoder("unsignedShort")
+	case reflect.Uint8:
+		return uintEncoder("unsignedByte")
+	case reflect.Float32:
+		return floatEncoder("float", 7, 32)
+	case reflect.Float64:
+		return floatEncoder("double", 16, 64)
+	case reflect.Struct:
+		// The codec is looked up on use, as t can be recursive (through
+		// pointers, slices or maps) and isn't cached yet.
+		return func(p *Profile, e *Element, v reflect.Value, depth int) error {
+			return compileStruct(t).encode(p, e, v, depth)
+		}
+	}
+	return func(p *Profile, e *Element, v reflect.Value, depth int) error {
+		return p.build(e, v.Interface(), depth)
+	}
+}
+
+func intEncoder(typ string) encodeFunc {
+	return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+		e.Type = p.xsdType(typ)
+		e.Text = strconv.FormatInt(v.Int(), 10)
+		return nil
+	}
+}
+
+func uintEncoder(typ string) encodeFunc {
+	return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+		e.Type = p.xsdType(typ)
+		e.Text = strconv.FormatUint(v.Uint(), 10)
+		return nil
+	}
+}
+
+func floatEncoder(typ string, prec, bits int) encodeFunc {
+	return func(p *Profile, e *Element, v reflect.Value, _ int) error {
+		e.Type = p.xsdType(typ)
+		e.Text = strconv.FormatFloat(v.Float(), 'e', prec, bits)
+		return nil
+	}
+}
+
+// fieldLoader returns the function that loads an item into a field of type t
+// with tag options opts.
+func fieldLoader(t reflect.Type, opts tagOptions) loadFunc {
+	if members := unionMembers(opts); members != nil && t.Kind() == reflect.Interface {
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			u, err := item.unionValue(members, o)
+			if err != nil {
+				return err
+			}
+			if u == nil {
+				v.Set(reflect.Zero(t))
+				return nil
+			}
+			if !reflect.TypeOf(u).AssignableTo(t) {
+				return errors.New("soap: can't assign " + reflect.TypeOf(u).String() + " to " + t.String())
+			}
+			v.Set(reflect.ValueOf(u))
+			return nil
+		}
+	}
+	if t == elementType {
+		return func(item *Element, v reflect.Value, _ *loadOpts) error {
+			if item.Nil {
+				v.Set(reflect.Zero(t))
+			} else {
+				v.Set(reflect.ValueOf(item.Clone()))
+			}
+			return nil
+		}
+	}
+	if t == readerType {
+		// The text is decoded as the reader is read.
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			if id, ok := item.ContentID(); ok {
+				r, err := openPart(item, id, t.String(), o)
+				if err == nil {
+					v.Set(reflect.ValueOf(r))
+				}
+				return err
+			}
+			if o.mode == Strict && skipNS(item.Type) != "base64Binary" {
+				return item.typeError("base64Binary")
+			}
+			if item.Nil {
+				v.Set(reflect.Zero(t))
+				return nil
+			}
+			if item.hasChildren() {
+				return item.badValue(t.String(), nil)
+			}
+			r := strings.NewReader(stripSpace(item.Text))
+			v.Set(reflect.ValueOf(base64.NewDecoder(base64.StdEncoding, r)))
+			return nil
+		}
+	}
+	if t == qnameType {
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			var (
+				q   QName
+				err error
+			)
+			if o.mode == Strict {
+				q, err = item.QName()
+			} else {
+				q, err = item.AsQName()
+			}
+			v.Set(reflect.ValueOf(q))
+			return err
+		}
+	}
+	if isUUIDType(t) {
+		return uuidLoader(t)
+	}
+	if typ := calendarTypes[t]; typ != "" {
+		return calendarLoader(typ)
+	}
+	if t == timeType {
+		// Option loc=NAME overrides the location of the Decoder.
+		var (
+			loc    *time.Location
+			locErr error
+		)
+		if name := opts.Get("loc"); name != "" {
+			if loc, locErr = time.LoadLocation(name); locErr != nil {
+				locErr = errors.New("soap: bad loc option: " + locErr.Error())
+			}
+		}
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			if locErr != nil {
+				return locErr
+			}
+			var (
+				tm  time.Time
+				err error
+			)
+			if o.mode == Strict {
+				tm, err = item.Time()
+			} else if loc != nil {
+				tm, err = item.AsTime(loc)
+			} else {
+				tm, err = item.AsTime(o.loc)
+			}
+			if o.utc {
+				tm = tm.UTC()
+			}
+			v.Set(reflect.ValueOf(tm))
+			return err
+		}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			if o.mode != Strict {
+				v.SetString(item.AsStr())
+				return nil
+			}
+			s, err := item.Str()
+			v.SetString(s)
+			return err
+		}
+	case reflect.Bool:
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			var (
+				b   bool
+				err error
+			)
+			if o.mode == Strict {
+				b, err = item.Bool()
+			} else {
+				b, err = item.AsBool()
+			}
+			v.SetBool(b)
+			return err
+		}
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8:
+		bits := t.Bits()
+		if t.Kind() == reflect.Int {
+			bits = 64 // written as xsd:long
+		}
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			var (
+				i   int64
+				err error
+			)
+			if o.mode == Strict {
+				i, err = item.Int(bits)
+			} else {
+				i, err = item.AsInt(bits)
+			}
+			if err == nil && v.OverflowInt(i) {
+				return item.badValue(t.String(), nil)
+			}
+			v.SetInt(i)
+			return err
+		}
+	case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8:
+		bits := t.Bits()
+		if t.Kind() == reflect.Uint {
+			bits = 64 // written as xsd:unsignedLong
+		}
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			var (
+				u   uint64
+				err error
+			)
+			if o.mode == Strict {
+				u, err = item.Uint(bits)
+			} else {
+				u, err = item.AsUint(bits)
+			}
+			if err == nil && v.OverflowUint(u) {
+				return item.badValue(t.String(), nil)
+			}
+			v.SetUint(u)
+			return err
+		}
+	case reflect.Float64, reflect.Float32:
+		bits := t.Bits()
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			var (
+				f   float64
+				err error
+			)
+			switch {
+			case o.mode == Strict:
+				f, err = item.Float(bits)
+			case o.lenientNumbers:
+				f, err = item.asLenientFloat(bits)
+			default:
+				f, err = item.AsFloat(bits)
+			}
+			v.SetFloat(f)
+			return err
+		}
+	case reflect.Struct:
+		// See fieldEncoder.
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			return compileStruct(t).load(item, v, o)
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return func(item *Element, v reflect.Value, o *loadOpts) error {
+				var (
+					b   []byte
+					err error
+				)
+				if id, ok := item.ContentID(); ok {
+					r, err := openPart(item, id, t.String(), o)
+					if err == nil {
+						b, err = io.ReadAll(r)
+						v.SetBytes(b)
+					}
+					return err
+				}
+				if o.mode == Strict {
+					b, err = item.Bytes()
+				} else {
+					b, err = item.AsBytes()
+				}
+				v.SetBytes(b)
+				return err
+			}
+		}
+		elem := fieldLoader(t.Elem(), opts)
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			// xsi:nil, unlike an empty array, makes a nil slice.
+			if item.Nil {
+				v.Set(reflect.Zero(t))
+				return nil
+			}
+			if o.mode == Strict && item.Type != "" && skipNS(item.Type) != "Array" {
+				return item.typeError("Array")
+			}
+			item.expand()
+			s := reflect.MakeSlice(t, len(item.Children), len(item.Children))
+			for i, c := range item.Children {
+				if c == nil {
+					continue
+				}
+				if err := elem(c, s.Index(i), o); err != nil {
+					return inPath(err, item.XMLName.Local)
+				}
+			}
+			v.Set(s)
+			return nil
+		}
+	case reflect.Map:
+		key, elem := fieldLoader(t.Key(), ""), fieldLoader(t.Elem(), opts)
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			// xsi:nil, unlike an empty map, makes a nil map.
+			if item.Nil {
+				v.Set(reflect.Zero(t))
+				return nil
+			}
+			if o.mode == Strict && item.Type != "" && skipNS(item.Type) != "Map" {
+				return item.typeError("Map")
+			}
+			item.expand()
+			m := reflect.MakeMapWithSize(t, len(item.Children))
+			for _, c := range item.Children {
+				if c == nil {
+					continue
+				}
+				ke, ve, err := c.MapItem()
+				if err != nil {
+					return inPath(err, item.XMLName.Local)
+				}
+				k, val := reflect.New(t.Key()).Elem(), reflect.New(t.Elem()).Elem()
+				if err := key(ke, k, o); err != nil {
+					return inPath(err, item.XMLName.Local)
+				}
+				if k.Kind() == reflect.Interface && !k.IsNil() && !k.Elem().Type().Comparable() {
+					return inPath(ke.badValue("map key", nil), item.XMLName.Local)
+				}
+				if err := elem(ve, val, o); err != nil {
+					return inPath(err, item.XMLName.Local)
+				}
+				m.SetMapIndex(k, val)
+			}
+			v.Set(m)
+			return nil
+		}
+	case reflect.Ptr:
+		elem := fieldLoader(t.Elem(), opts)
+		return func(item *Element, v reflect.Value, o *loadOpts) error {
+			if item.Nil {
+				v.Set(reflect.Zero(t))
+				return nil
+			}
+			p := reflect.New(t.Elem())
+			err := elem(item, p.Elem(), o)
+			v.Set(p)
+			return err
+		}
+	}
+	if t.Kind() == reflect.Interface {
+		return substituteLoader(t)
+	}
+	return func(*Element, reflect.Value, *loadOpts) error {
+		return errors.New("soap: unsupported field type " + t.String())
+	}
+}
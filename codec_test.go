@@ -0,0 +1,131 @@
+package soap
+
+import "testing"
+
+type node struct {
+	Name string
+	Next *node  `soap:"Next,omitempty"`
+	Kids []node `soap:"Kids,omitempty"`
+}
+
+func TestRecursiveStruct(t *testing.T) {
+	n := node{Name: "a", Next: &node{Name: "b"}, Kids: []node{{Name: "c"}}}
+	e := MakeElement("N", n)
+	var r node
+	if err := e.LoadStruct(&r, false); err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "a" || r.Next == nil || r.Next.Name != "b" ||
+		len(r.Kids) != 1 || r.Kids[0].Name != "c" {
+		t.Fatalf("loaded %+v", r)
+	}
+	c, err := CompileCodec[node]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ce, err := c.Encode("N", &n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(ce, e) {
+		t.Fatal("Codec.Encode differs from MakeElement")
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	if _, err := CompileCodec[int](); err == nil {
+		t.Error("CompileCodec of int succeeded")
+	}
+	type deep struct{ Next *deep }
+	c, err := CompileCodec[deep]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Profile = &Profile{MaxDepth: 3}
+	d := &deep{&deep{&deep{&deep{}}}}
+	if _, err := c.Encode("d", d); err != ErrTooDeep {
+		t.Errorf("got %v, want ErrTooDeep", err)
+	}
+	type pick struct {
+		A string `soap:"A,choice=x"`
+		B string `soap:"B,choice=x"`
+	}
+	p, _ := CompileCodec[pick]()
+	if _, err := p.Encode("p", &pick{"a", "b"}); err == nil {
+		t.Error("both choice alternatives encoded")
+	}
+	type bad struct{ C chan int }
+	b, _ := CompileCodec[bad]()
+	if _, err := b.Encode("b", &bad{make(chan int)}); err == nil {
+		t.Error("channel encoded")
+	}
+}
+
+func TestCodecRoundTripSizes(t *testing.T) {
+	type S struct {
+		F32 float32
+		F64 float64
+		I   int
+		U   uint
+		I8  int8
+		U16 uint16
+	}
+	c, err := CompileCodec[S]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := S{1.25, 2.5, -3, 4, -5, 6}
+	e, err := c.Encode("S", &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []DecodeMode{Strict, Coerce, Lenient} {
+		var out S
+		if err := c.Load(e, &out, mode); err != nil {
+			t.Fatalf("mode %d: %v", mode, err)
+		}
+		if out != in {
+			t.Errorf("mode %d: got %+v", mode, out)
+		}
+		out = S{}
+		if err := MakeElement("S", in).Load(&out, mode); err != nil {
+			t.Fatalf("mode %d: %v", mode, err)
+		}
+		if out != in {
+			t.Errorf("mode %d: got %+v from MakeElement", mode, out)
+		}
+	}
+}
+
+func TestLoadAbsentLenient(t *testing.T) {
+	type S struct {
+		Name string
+		I    int
+		P    *int
+		L    []string
+		M    map[string]int
+	}
+	c, err := CompileCodec[S]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 7
+	in := S{"a", 1, &n, []string{"x"}, map[string]int{"k": 2}}
+	e := MakeElement("S", struct{ Name string }{"b"})
+	for _, load := range []func(*S) error{
+		func(s *S) error { return c.Load(e, s, Lenient) },
+		func(s *S) error { return e.Load(s, Lenient) },
+	} {
+		out := in
+		if err := load(&out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Name != "b" || out.I != 1 || out.P != &n || len(out.L) != 1 ||
+			out.M["k"] != 2 {
+			t.Errorf("got %+v", out)
+		}
+	}
+	if err := e.Load(&S{}, Coerce); err == nil {
+		t.Error("absent fields loaded in Coerce mode")
+	}
+}
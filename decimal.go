@@ -0,0 +1,167 @@
+package soap
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// digitsFacet holds the totalDigits and fractionDigits options of a decimal
+// field (of float or string type), written as xsd:decimal.
+type digitsFacet struct {
+	name            string // element name
+	total, fraction int    // -1 if not given
+	round           bool   // round to fraction digits instead of an error
+	err             error  // bad option
+}
+
+// newDigitsFacet returns the facet given by opts for a field of type t or
+// nil if opts don't give one.
+func newDigitsFacet(t reflect.Type, name string, opts tagOptions) *digitsFacet {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64, reflect.String:
+	default:
+		return nil
+	}
+	total, fraction := opts.Get("totalDigits"), opts.Get("fractionDigits")
+	if total == "" && fraction == "" {
+		return nil
+	}
+	d := &digitsFacet{name: name, round: opts.Contains("round")}
+	if d.total, d.err = parseLimit("totalDigits", total, -1); d.err == nil {
+		d.fraction, d.err = parseLimit("fractionDigits", fraction, -1)
+	}
+	return d
+}
+
+var errNotDecimal = errors.New("soap: not a decimal number")
+
+// apply checks decimal s against the facet, rounding it if the round option
+// was given. It returns s in canonical form (without leading zeros and
+// trailing zeros of the fraction).
+func (d *digitsFacet) apply(s string) (string, error) {
+	if d.err != nil {
+		return "", d.err
+	}
+	s = strings.TrimSpace(s)
+	if !isDecimal(s) {
+		return "", errNotDecimal
+	}
+	n := 0 // fraction digits
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		n = len(strings.TrimRight(s[i+1:], "0"))
+	}
+	if d.fraction >= 0 && n > d.fraction {
+		if !d.round {
+			return "", d.error("fractionDigits", d.fraction, s)
+		}
+		n = d.fraction
+	}
+	r, _ := new(big.Rat).SetString(s)
+	s = r.FloatString(n) // rounds halves away from zero
+	if strings.IndexByte(s, '.') != -1 {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	if d.total >= 0 && countDigits(s) > d.total {
+		return "", d.error("totalDigits", d.total, s)
+	}
+	return s, nil
+}
+
+func (d *digitsFacet) error(opt string, limit int, s string) error {
+	return &FacetError{opt, strconv.Itoa(limit), s, d.name}
+}
+
+// isDecimal reports whether s is in the lexical space of xsd:decimal.
+func isDecimal(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	digits, dot := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// countDigits returns the number of significant digits of canonical decimal
+// s, as counted by totalDigits.
+func countDigits(s string) int {
+	s = strings.TrimLeft(strings.TrimPrefix(s, "-"), "0.")
+	return len(strings.Replace(s, ".", "", 1))
+}
+
+// encode writes v of the decimal field into e.
+func (d *digitsFacet) encode(p *Profile, e *Element, v reflect.Value) error {
+	var s string
+	if v.Kind() == reflect.String {
+		s = v.String()
+	} else {
+		bits := 64
+		if v.Kind() == reflect.Float32 {
+			bits = 32
+		}
+		s = strconv.FormatFloat(v.Float(), 'f', -1, bits)
+	}
+	dec, err := d.apply(s)
+	if err == errNotDecimal {
+		return &ValueError{Value: s, Type: "decimal", GoType: "string", Path: d.name}
+	}
+	if err != nil {
+		return err
+	}
+	e.Type = p.xsdType("decimal")
+	e.Text = dec
+	return nil
+}
+
+// loader returns the loadFunc of the decimal field of type t.
+func (d *digitsFacet) loader(t reflect.Type) loadFunc {
+	return func(item *Element, v reflect.Value, o *loadOpts) error {
+		if o.mode == Strict && skipNS(item.Type) != "decimal" {
+			return item.typeError("decimal")
+		}
+		if item.hasChildren() {
+			return item.badValue(t.String(), nil)
+		}
+		text := item.Text
+		if o.lenientNumbers && o.mode != Strict {
+			text = normalizeNumber(text)
+		}
+		s, err := d.apply(text)
+		if err == errNotDecimal && o.mode != Strict {
+			// Coerce floats written in exponent notation.
+			if f, perr := strconv.ParseFloat(strings.TrimSpace(text), 64); perr == nil {
+				s, err = d.apply(strconv.FormatFloat(f, 'f', -1, 64))
+			}
+		}
+		if err == errNotDecimal {
+			return item.badValue(t.String(), err)
+		}
+		if err != nil {
+			return err
+		}
+		if t.Kind() == reflect.String {
+			v.SetString(s)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return item.badValue(t.String(), err)
+		}
+		v.SetFloat(f)
+		return nil
+	}
+}
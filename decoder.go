@@ -0,0 +1,525 @@
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// DefaultMaxDepth is the maximum nesting of element trees used when
+// Decoder.MaxDepth or Profile.MaxDepth is zero.
+const DefaultMaxDepth = 1000
+
+// ErrTooDeep is returned when an element tree is nested deeper than allowed.
+var ErrTooDeep = errors.New("soap: element tree too deep")
+
+// DecodeMode determines how values of elements are converted to Go types.
+type DecodeMode int
+
+const (
+	// Strict requires SOAP types matching Go types, as the typed accessors
+	// (Str, Int, ...) do. Missing struct fields are errors.
+	Strict DecodeMode = iota
+
+	// Coerce converts between numbers, strings and booleans with range
+	// checks, as the As* accessors do. Missing struct fields are errors.
+	Coerce
+
+	// Lenient converts like Coerce, but missing struct fields are left
+	// unchanged, as encoding/xml does.
+	Lenient
+)
+
+// A Decoder holds settings used to convert Element trees into Go values. The
+// zero value is ready to use.
+type Decoder struct {
+	// MaxDepth limits nesting of decoded trees (DefaultMaxDepth if 0), to
+	// protect against adversarial input.
+	MaxDepth int
+
+	// Workers is the number of goroutines used to decode items of arrays
+	// that have at least ParallelArrayMin items. Arrays are decoded
+	// sequentially if Workers < 2.
+	Workers int
+
+	// MaxBytes limits the approximate memory used by trees built by Decode
+	// and UnmarshalXML (unlimited if 0). Exceeding it aborts decoding with
+	// a *BudgetError.
+	MaxBytes int64
+
+	// Lazy makes Decode build only the top level of the tree. Children of
+	// other elements are decoded when they are first accessed (see
+	// Element.Expand), so extracting a few fields from a huge document
+	// doesn't build all of it. The whole document is kept in memory and
+	// MaxBytes limits its size.
+	Lazy bool
+
+	// Location is assumed for times without zone information decoded by
+	// Value, and loaded by Load in Coerce and Lenient modes (time.Local if
+	// nil). Fields of type
+	// time.Time can override it with the loc tag option, e.g.
+	// `soap:"Created,loc=UTC"`.
+	Location *time.Location
+
+	// UTC makes decoded and loaded times converted to UTC.
+	UTC bool
+
+	// Mixed makes Decode and UnmarshalXML fill Element.Content, preserving
+	// the order of text and child elements. It is ignored if Lazy is set.
+	// Elements in scope of xml:space="preserve" are always decoded this
+	// way (unless Lazy is set), so whitespace between their children is
+	// written back in place. Text is never trimmed.
+	Mixed bool
+
+	// Comments works like Mixed but also keeps comments and processing
+	// instructions inside the root element as Content nodes, so they are
+	// written back by Encoder. It is ignored if Lazy is set.
+	Comments bool
+
+	// Parts returns MIME parts of XOP (MTOM) messages by their Content-ID.
+	// Load uses it for []byte and io.Reader fields of elements that hold
+	// an xop:Include reference (see Element.ContentID) instead of base64
+	// text. Such fields can't be loaded if Parts is nil.
+	Parts func(contentID string) (io.Reader, error)
+
+	// LenientNumbers makes Load in Coerce and Lenient modes accept float
+	// and decimal fields written in locale formats of broken servers: with
+	// surrounding white space, thousands separators and comma decimal
+	// separators (e.g. "1 234,56"). A single comma is always taken as the
+	// decimal separator.
+	LenientNumbers bool
+}
+
+// ParallelArrayMin is the minimum number of array items for which Decoder
+// uses its Workers.
+const ParallelArrayMin = 1000
+
+// DefaultDecoder is used by Element.Value.
+var DefaultDecoder = &Decoder{}
+
+// Load works like Element.Load but uses settings of dec.
+func (dec *Decoder) Load(e *Element, sp interface{}, mode DecodeMode) error {
+	p := reflect.ValueOf(sp)
+	if p.Kind() != reflect.Ptr || p.Type().Elem().Kind() != reflect.Struct {
+		return errors.New("soap: argument should be a pointer to the struct")
+	}
+	s := p.Elem()
+	return compileStruct(s.Type()).load(e, s, dec.loadOpts(mode))
+}
+
+func (dec *Decoder) loadOpts(mode DecodeMode) *loadOpts {
+	return &loadOpts{mode, dec.location(), dec.UTC, dec.Parts, dec.LenientNumbers}
+}
+
+func (dec *Decoder) location() *time.Location {
+	if dec.Location == nil {
+		return time.Local
+	}
+	return dec.Location
+}
+
+func (dec *Decoder) maxDepth() int {
+	if dec.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return dec.MaxDepth
+}
+
+// Value works like Element.Value but uses settings of dec. The tree is
+// traversed using an explicit stack, so deep trees can't overflow the
+// goroutine stack.
+func (dec *Decoder) Value(e *Element) (interface{}, error) {
+	return dec.value(e, lookupSchemaElem(e.XMLName), dec.maxDepth())
+}
+
+// value returns the value of e, declared by d, nested at most max levels.
+func (dec *Decoder) value(e *Element, d *schemaElem, max int) (interface{}, error) {
+	var stack []*valueFrame
+	for {
+		if len(stack) == max {
+			return nil, ErrTooDeep
+		}
+		v, f, err := dec.openValue(e, d, max-len(stack))
+		if err != nil {
+			for i := len(stack) - 1; i >= 0; i-- {
+				err = inPath(err, stack[i].e.XMLName.Local)
+			}
+			return nil, err
+		}
+		if f != nil {
+			stack = append(stack, f)
+		} else if len(stack) == 0 {
+			return v, nil
+		} else {
+			stack[len(stack)-1].add(v)
+		}
+		// Find the next element to decode, closing completed frames.
+		for {
+			top := stack[len(stack)-1]
+			var ok bool
+			e, d, ok, err = top.next()
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				break
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return top.result(), nil
+			}
+			stack[len(stack)-1].add(top.result())
+		}
+	}
+}
+
+// valueFrame holds the state of a compound value being decoded.
+type valueFrame struct {
+	e   *Element
+	d   *schemaElem
+	typ string
+	i   int         // number of decoded values
+	key interface{} // Map key waiting for its value
+
+	m map[string]interface{}
+	a []interface{}
+	k map[interface{}]interface{}
+}
+
+// openValue returns the value of a scalar element or a frame for a compound
+// one. Large arrays are decoded in place if dec has Workers. The tree of e
+// can have at most max levels.
+func (dec *Decoder) openValue(e *Element, d *schemaElem, max int) (interface{}, *valueFrame, error) {
+	if e.Nil {
+		return nil, nil, nil
+	}
+	e.expand()
+	typ := d.typeOf(e)
+	f := &valueFrame{e: e, d: d, typ: typ}
+	switch typ {
+	case "Struct":
+		f.m = make(map[string]interface{}, len(e.Children))
+	case "Array":
+		if dec.Workers > 1 && len(e.Children) >= ParallelArrayMin {
+			v, err := dec.parallelArray(e, d.field("item"), max-1)
+			return v, nil, err
+		}
+		f.a = make([]interface{}, 0, len(e.Children))
+	case "Map":
+		f.k = make(map[interface{}]interface{}, len(e.Children))
+	default:
+		v, err := e.scalarValue(typ, dec.location())
+		if t, ok := v.(time.Time); ok && dec.UTC {
+			v = t.UTC()
+		}
+		if err == nil && v == nil {
+			err = errors.New("soap: unknown type: " + e.Type)
+		}
+		return v, nil, err
+	}
+	return nil, f, nil
+}
+
+// parallelArray decodes items of array e, declared by d, using dec.Workers
+// goroutines, each decoding a contiguous range of items. Items can have at
+// most max levels.
+func (dec *Decoder) parallelArray(e *Element, d *schemaElem, max int) (interface{}, error) {
+	if max < 1 {
+		return nil, ErrTooDeep
+	}
+	items := e.Children
+	for _, c := range items {
+		if c.XMLName.Local != "item" {
+			return nil, errors.New(
+				"soap: bad element '" + c.XMLName.Local + "'in array",
+			)
+		}
+	}
+	a := make([]interface{}, len(items))
+	n := dec.Workers
+	chunk := (len(items) + n - 1) / n
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		lo, hi := w*chunk, (w+1)*chunk
+		if hi > len(items) {
+			hi = len(items)
+		}
+		wg.Add(1)
+		go func(w, lo, hi int) {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				v, err := dec.value(items[i], d, max)
+				if err != nil {
+					errs[w] = inPath(err, e.XMLName.Local)
+					return
+				}
+				a[i] = v
+			}
+		}(w, lo, hi)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// next returns the next child element to decode and its declaration. It
+// returns ok == false if all children were decoded.
+func (f *valueFrame) next() (c *Element, d *schemaElem, ok bool, err error) {
+	switch f.typ {
+	case "Struct":
+		if f.i < len(f.e.Children) {
+			c = f.e.Children[f.i]
+			return c, f.d.field(c.XMLName.Local), true, nil
+		}
+
+	case "Array":
+		if f.i < len(f.e.Children) {
+			c = f.e.Children[f.i]
+			if c.XMLName.Local != "item" {
+				return nil, nil, false, errors.New(
+					"soap: bad element '" + c.XMLName.Local + "'in array",
+				)
+			}
+			return c, f.d.field("item"), true, nil
+		}
+
+	case "Map":
+		if f.i < 2*len(f.e.Children) {
+			key, val, err := f.e.Children[f.i/2].MapItem()
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if f.i%2 == 0 {
+				return key, nil, true, nil
+			}
+			if t := reflect.TypeOf(f.key); t != nil && !t.Comparable() {
+				return nil, nil, false, errors.New(
+					"soap: map key of type " + t.String() + " isn't hashable",
+				)
+			}
+			return val, nil, true, nil
+		}
+	}
+	return nil, nil, false, nil
+}
+
+// add stores v as the value of the last child returned by next.
+func (f *valueFrame) add(v interface{}) {
+	switch f.typ {
+	case "Struct":
+		f.m[f.e.Children[f.i].XMLName.Local] = v
+
+	case "Array":
+		f.a = append(f.a, v)
+
+	case "Map":
+		if f.i%2 == 0 {
+			f.key = v
+		} else {
+			f.k[f.key] = v
+		}
+	}
+	f.i++
+}
+
+func (f *valueFrame) result() interface{} {
+	switch f.typ {
+	case "Struct":
+		return f.m
+	case "Array":
+		return f.a
+	}
+	return f.k
+}
+
+// UnmarshalXML implements xml.Unmarshaler. The tree is built directly from
+// tokens read from d (without encoding/xml reflection), using settings of
+// DefaultDecoder.
+func (e *Element) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return DefaultDecoder.decodeElement(d, start, e)
+}
+
+// Decode reads the first XML element from r and returns it as Element tree.
+func (dec *Decoder) Decode(r io.Reader) (*Element, error) {
+	e, err := dec.decode(r)
+	if err != nil {
+		atomic.AddInt64(&stats.DecodeErrors, 1)
+	} else {
+		atomic.AddInt64(&stats.Decoded, 1)
+	}
+	return e, err
+}
+
+func (dec *Decoder) decode(r io.Reader) (*Element, error) {
+	if dec.Lazy {
+		return dec.decodeLazy(r)
+	}
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			e := newElement()
+			if err := dec.decodeElement(d, start, e); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	}
+}
+
+// decodeElement reads the content of element that begins with start into e.
+func (dec *Decoder) decodeElement(d *xml.Decoder, start xml.StartElement, e *Element) error {
+	max := dec.maxDepth()
+	names := make(interner)
+	e.setStart(start, names)
+	e.scope = inScope(nil, start)
+	used := e.size()
+	mixed := dec.Mixed || dec.Comments
+	stack := []*Element{e}
+	preserve := []bool{e.preserveSpace(false)}
+	// Char data of open elements, converted to Text once at their end tags.
+	// Buffers are reused by following elements of the same depth.
+	texts := [][]byte{nil}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		top := stack[len(stack)-1]
+		ordered := mixed || preserve[len(preserve)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == max {
+				return ErrTooDeep
+			}
+			c := newElement()
+			c.setStart(t, names)
+			c.scope = inScope(top.scope, t)
+			used += c.size()
+			top.Children = append(top.Children, c)
+			if ordered {
+				top.Content = append(top.Content, Node{Elem: c})
+			}
+			stack = append(stack, c)
+			preserve = append(preserve, c.preserveSpace(preserve[len(preserve)-1]))
+			if len(texts) < len(stack) {
+				texts = append(texts, nil)
+			} else {
+				texts[len(stack)-1] = texts[len(stack)-1][:0]
+			}
+
+		case xml.EndElement:
+			if text := texts[len(stack)-1]; len(text) != 0 {
+				top.Text = string(text)
+			}
+			stack = stack[:len(stack)-1]
+			preserve = preserve[:len(preserve)-1]
+			if len(stack) == 0 {
+				return nil
+			}
+
+		case xml.CharData:
+			texts[len(stack)-1] = append(texts[len(stack)-1], t...)
+			used += int64(len(t))
+			if ordered {
+				top.Content = append(top.Content, Node{Text: string(t)})
+			}
+
+		case xml.Comment:
+			if dec.Comments {
+				top.Content = append(top.Content, Node{Text: string(t), Comment: true})
+				used += int64(len(t))
+			}
+
+		case xml.ProcInst:
+			if dec.Comments {
+				top.Content = append(top.Content, Node{Text: string(t.Inst), Target: t.Target})
+				used += int64(len(t.Target) + len(t.Inst))
+			}
+		}
+		if dec.MaxBytes > 0 && used > dec.MaxBytes {
+			return &BudgetError{dec.MaxBytes}
+		}
+	}
+}
+
+// setStart sets name and attributes of e from start. Names and types are
+// interned in names.
+func (e *Element) setStart(start xml.StartElement, names interner) {
+	e.XMLName.Space = names.intern(start.Name.Space)
+	e.XMLName.Local = names.intern(start.Name.Local)
+	for _, a := range start.Attr {
+		switch {
+		case a.Name.Space == NamespaceXSI && a.Name.Local == "type":
+			e.Type = names.intern(a.Value)
+		case a.Name.Space == NamespaceXSI && a.Name.Local == "nil":
+			e.Nil, _ = strconv.ParseBool(a.Value)
+		case a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns":
+			e.NS = append(e.NS, a)
+		default:
+			a.Name.Space = names.intern(a.Name.Space)
+			a.Name.Local = names.intern(a.Name.Local)
+			e.Attrs = append(e.Attrs, a)
+		}
+	}
+}
+
+// preserveSpace reports whether xml:space="preserve" applies to e. inherited
+// is the value for its parent.
+func (e *Element) preserveSpace(inherited bool) bool {
+	for _, a := range e.Attrs {
+		if a.Name.Space == NamespaceXML && a.Name.Local == "space" {
+			return a.Value == "preserve"
+		}
+	}
+	return inherited
+}
+
+// size returns approximate memory used by e, excluding its text and
+// children, plus the slot in the Children slice of its parent.
+func (e *Element) size() int64 {
+	n := int64(unsafe.Sizeof(*e)+unsafe.Sizeof(e)) +
+		int64(len(e.XMLName.Space)+len(e.XMLName.Local)+len(e.Type))
+	for _, a := range e.Attrs {
+		n += int64(unsafe.Sizeof(a)) + int64(len(a.Value))
+	}
+	for _, a := range e.NS {
+		n += int64(unsafe.Sizeof(a)) + int64(len(a.Value))
+	}
+	return n
+}
+
+// maxInterned limits the number of distinct strings interned while decoding
+// one document.
+const maxInterned = 1024
+
+// interner deduplicates element names and types repeated in a document, so
+// retained trees share one copy of every name instead of one per element.
+type interner map[string]string
+
+func (in interner) intern(s string) string {
+	if t, ok := in[s]; ok {
+		return t
+	}
+	if len(in) < maxInterned {
+		in[s] = s
+	}
+	return s
+}
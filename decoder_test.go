@@ -0,0 +1,194 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// benchResponse returns an rpc/encoded envelope of about 100 KB.
+func benchResponse() []byte {
+	type resp struct{ Records []benchRecord }
+	return new(Encoder).AppendEnvelope(nil, nil, MakeElement("resp", resp{benchRecords(230)}))
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := benchResponse()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e, err := new(Decoder).Decode(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		e.Release()
+	}
+}
+
+// xmlNode is a generic tree decoded by encoding/xml reflection, the way
+// Element was decoded before it implemented xml.Unmarshaler.
+type xmlNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []xmlNode  `xml:",any"`
+}
+
+func BenchmarkDecodeEncodingXML(b *testing.B) {
+	data := benchResponse()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var n xmlNode
+		if err := xml.Unmarshal(data, &n); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// wideText returns an element with n children interleaved with text.
+func wideText(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("<p>")
+	for i := 0; i < n; i++ {
+		b.WriteString("\n\tsome text <b>bold</b> more")
+	}
+	b.WriteString("\n</p>")
+	return b.Bytes()
+}
+
+func TestDecodeWideText(t *testing.T) {
+	data := wideText(3)
+	want := strings.Repeat("\n\tsome text  more", 3) + "\n"
+	for _, dec := range []*Decoder{{}, {Lazy: true}, {Mixed: true}} {
+		e, err := dec.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Text != want || len(e.Children) != 3 || e.Children[0].Text != "bold" {
+			t.Errorf("lazy %t, mixed %t: text %q", dec.Lazy, dec.Mixed, e.Text)
+		}
+	}
+}
+
+func BenchmarkDecodeWideText(b *testing.B) {
+	data := wideText(5000)
+	for _, dec := range []*Decoder{{}, {Lazy: true}, {Mixed: true}} {
+		name := "Tree"
+		switch {
+		case dec.Lazy:
+			name = "Lazy"
+		case dec.Mixed:
+			name = "Mixed"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				e, err := dec.Decode(bytes.NewReader(data))
+				if err != nil {
+					b.Fatal(err)
+				}
+				e.Release()
+			}
+		})
+	}
+}
+
+var fuzzSeeds = []string{
+	`<a xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="xsd:int">12</a>`,
+	`<m xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="ns2:Map"><item><key>k</key><value>v</value></item><item/></m>`,
+	`<r><Name>x</Name><Count>1</Count><Tags><item>a</item></Tags><M><item><key>1</key></item></M><Q>p:x</Q></r>`,
+	`<a xml:space="preserve"> <b xsi:nil="true" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"/> <!--c--></a>`,
+}
+
+// FuzzDecode checks that decoding, converting and encoding arbitrary input
+// returns errors instead of panicking.
+func FuzzDecode(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, dec := range []*Decoder{{MaxDepth: 50}, {Mixed: true, Comments: true}, {Lazy: true}} {
+			e, err := dec.Decode(bytes.NewReader(data))
+			if err != nil {
+				continue
+			}
+			dec.Value(e)
+			e.Get(0)
+			e.Get("item")
+			for _, c := range e.Children {
+				if c != nil {
+					c.MapItem()
+				}
+			}
+			new(Encoder).AppendElement(nil, e)
+			e.Release()
+		}
+	})
+}
+
+type fuzzStruct struct {
+	Name  string
+	Count int64
+	Small int8
+	Ratio float32
+	Tags  []string
+	M     map[string]int64
+	Any   interface{}
+	Q     QName
+	ID    [16]byte
+	Year  GYear
+	Data  []byte
+	Next  *fuzzStruct
+}
+
+// FuzzLoad checks that loading arbitrary input into structs returns errors
+// instead of panicking.
+func FuzzLoad(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		e, err := new(Decoder).Decode(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, mode := range []DecodeMode{Strict, Coerce, Lenient} {
+			var v fuzzStruct
+			e.Load(&v, mode)
+		}
+	})
+}
+
+func TestParallelArraySchema(t *testing.T) {
+	s := NewSchema("")
+	if err := s.Add("ParOrder", struct{ Lines []int64 }{}); err != nil {
+		t.Fatal(err)
+	}
+	RegisterSchema(s)
+	doc := "<ParOrder><Lines>" + strings.Repeat("<item>7</item>", ParallelArrayMin) +
+		"</Lines></ParOrder>"
+	var values []interface{}
+	for _, workers := range []int{0, 4} {
+		dec := &Decoder{Workers: workers}
+		e, err := dec.Decode(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := dec.Value(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := v.(map[string]interface{})["Lines"].([]interface{})
+		if lines[0] != int64(7) {
+			t.Errorf("workers %d: item %#v", workers, lines[0])
+		}
+		values = append(values, v)
+	}
+	if !reflect.DeepEqual(values[0], values[1]) {
+		t.Error("values differ by number of workers")
+	}
+}
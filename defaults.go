@@ -0,0 +1,129 @@
+package soap
+
+import "time"
+
+// The *Or accessors return the value of e converted like As does (so
+// regardless of its xsi:type, with times in the location of DefaultDecoder)
+// or def if e is nil (e.g. returned by Get for a missing field) or has
+// xsi:nil set. Text that can't be converted gives the zero value, not def,
+// so a malformed value isn't taken for a missing one. The Must* accessors
+// panic instead of returning an error, which is handy in tests.
+
+// valueOr implements the *Or accessors.
+func valueOr[T Scalar](e *Element, def T) T {
+	if e == nil || e.Nil {
+		return def
+	}
+	var v T
+	if e.scanAs(&v) != nil {
+		var zero T
+		return zero
+	}
+	return v
+}
+
+func (e *Element) StrOr(def string) string {
+	return valueOr(e, def)
+}
+
+func (e *Element) BoolOr(def bool) bool {
+	return valueOr(e, def)
+}
+
+func (e *Element) Int64Or(def int64) int64 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Int32Or(def int32) int32 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Int16Or(def int16) int16 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Int8Or(def int8) int8 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Uint64Or(def uint64) uint64 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Uint32Or(def uint32) uint32 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Uint16Or(def uint16) uint16 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Uint8Or(def uint8) uint8 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Float64Or(def float64) float64 {
+	return valueOr(e, def)
+}
+
+func (e *Element) Float32Or(def float32) float32 {
+	return valueOr(e, def)
+}
+
+func (e *Element) TimeOr(def time.Time) time.Time {
+	return valueOr(e, def)
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (e *Element) MustStr() string {
+	v, err := e.Str()
+	must(err)
+	return v
+}
+
+func (e *Element) MustBool() bool {
+	v, err := e.Bool()
+	must(err)
+	return v
+}
+
+func (e *Element) MustInt64() int64 {
+	v, err := e.Int64()
+	must(err)
+	return v
+}
+
+func (e *Element) MustInt32() int32 {
+	v, err := e.Int32()
+	must(err)
+	return v
+}
+
+func (e *Element) MustUint64() uint64 {
+	v, err := e.Uint64()
+	must(err)
+	return v
+}
+
+func (e *Element) MustUint32() uint32 {
+	v, err := e.Uint32()
+	must(err)
+	return v
+}
+
+func (e *Element) MustFloat64() float64 {
+	v, err := e.Float64()
+	must(err)
+	return v
+}
+
+func (e *Element) MustTime() time.Time {
+	v, err := e.Time()
+	must(err)
+	return v
+}
@@ -0,0 +1,92 @@
+package soap
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrAccessors(t *testing.T) {
+	e, err := new(Decoder).Decode(strings.NewReader(
+		`<R xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+			`<S>s</S><B>true</B><I>-7</I><U>7</U><F>1.5</F>` +
+			`<T>2020-01-02T03:04:05Z</T><L>2020-01-02T03:04:05</L><N xsi:nil="true"/><X>x</X></R>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(name string) *Element {
+		c, _ := e.Get(name)
+		return c
+	}
+	if v := get("S").StrOr("d"); v != "s" {
+		t.Errorf("StrOr: %q", v)
+	}
+	if v := get("B").BoolOr(false); !v {
+		t.Error("BoolOr: false")
+	}
+	if v := get("I").Int64Or(1); v != -7 {
+		t.Errorf("Int64Or: %d", v)
+	}
+	if v := get("I").Int32Or(1); v != -7 {
+		t.Errorf("Int32Or: %d", v)
+	}
+	if v := get("U").Uint64Or(1); v != 7 {
+		t.Errorf("Uint64Or: %d", v)
+	}
+	if v := get("U").Uint32Or(1); v != 7 {
+		t.Errorf("Uint32Or: %d", v)
+	}
+	if v := get("F").Float64Or(1); v != 1.5 {
+		t.Errorf("Float64Or: %g", v)
+	}
+	want := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if v := get("T").TimeOr(time.Time{}); !v.Equal(want) {
+		t.Errorf("TimeOr: %v", v)
+	}
+	for _, name := range []string{"N", "Missing"} {
+		if v := get(name).Int64Or(1); v != 1 {
+			t.Errorf("Int64Or of %s: %d", name, v)
+		}
+		if v := get(name).StrOr("d"); v != "d" {
+			t.Errorf("StrOr of %s: %q", name, v)
+		}
+	}
+	if v := get("I").Int16Or(1); v != -7 {
+		t.Errorf("Int16Or: %d", v)
+	}
+	if v := get("I").Int8Or(1); v != -7 {
+		t.Errorf("Int8Or: %d", v)
+	}
+	if v := get("U").Uint16Or(1); v != 7 {
+		t.Errorf("Uint16Or: %d", v)
+	}
+	if v := get("U").Uint8Or(1); v != 7 {
+		t.Errorf("Uint8Or: %d", v)
+	}
+	if v := get("F").Float32Or(1); v != 1.5 {
+		t.Errorf("Float32Or: %g", v)
+	}
+	// Times without zone are in the location of DefaultDecoder, as for As.
+	defer func(loc *time.Location) { DefaultDecoder.Location = loc }(DefaultDecoder.Location)
+	DefaultDecoder.Location = time.FixedZone("X", 3600)
+	want = time.Date(2020, 1, 2, 3, 4, 5, 0, DefaultDecoder.Location)
+	if v := get("L").TimeOr(time.Time{}); !v.Equal(want) {
+		t.Errorf("TimeOr: %v, want %v", v, want)
+	}
+	if v := mustAs[time.Time](t, e, "L"); !v.Equal(want) {
+		t.Errorf("As: %v, want %v", v, want)
+	}
+	if v := get("X").Int64Or(1); v != 0 {
+		t.Errorf("Int64Or of malformed value: %d", v)
+	}
+}
+
+func mustAs[T Scalar](t *testing.T, e *Element, key string) T {
+	t.Helper()
+	v, err := As[T](e, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -1,20 +1,42 @@
 package soap
 
 import (
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
-	"fmt"
+	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// NamespaceXSI is the namespace of xsi:type and xsi:nil attributes.
+const NamespaceXSI = "http://www.w3.org/2001/XMLSchema-instance"
+
 const (
-	timeFormatSOAP = "2006-01-02T15:04:05.000000000-07:00"
-	timeFormatSQL  = "2006-01-02 15:04:05"
+	timeFormatSOAP    = "2006-01-02T15:04:05.000000000-07:00"
+	timeFormatSOAPUTC = "2006-01-02T15:04:05.000000000Z"
+	timeFormatNoZone  = "2006-01-02T15:04:05.999999999"
+	timeFormatSQL     = "2006-01-02 15:04:05"
 )
 
+// parseDateTime parses the xsd:dateTime lexical form, in which fractional
+// seconds and the zone (Z or an offset) are optional. Times without zone are
+// in loc.
+func parseDateTime(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		var err1 error
+		if t, err1 = time.ParseInLocation(timeFormatNoZone, s, loc); err1 == nil {
+			err = nil
+		}
+	}
+	return t, err
+}
+
 // An Element represents one XML/SOAP data element as Go struct. You can use it
 // to build your own SOAP request/reply and use encoding/xml to
 // marshal/unmarshal it into/from XML document.
@@ -25,145 +47,361 @@ type Element struct {
 	Type string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr,omitempty"`
 	Nil  bool   `xml:"http://www.w3.org/2001/XMLSchema-instance nil,attr,omitempty"`
 
+	// Attrs holds attributes other than xsi:type, xsi:nil and namespace
+	// declarations, so they survive decoding and encoding.
+	Attrs []xml.Attr `xml:",any,attr"`
+
+	// NS holds namespace declarations of the element as decoded
+	// (Name.Space "xmlns" and the prefix in Name.Local, or Name.Local
+	// "xmlns" for the default namespace). Encoder writes them back and
+	// uses the declared prefixes for names in their namespaces.
+	NS []xml.Attr `xml:"-"`
+
 	Text     string     `xml:",chardata"`
 	Children []*Element `xml:",any"`
+
+	// Content is the ordered content of elements with mixed content,
+	// filled by a Decoder with Mixed or Comments set. If not empty, Encoder writes it
+	// instead of Text and Children.
+	Content []Node `xml:"-"`
+
+	index atomic.Pointer[childIndex] // used by Get, nil if not built
+	lazy  *lazySpan                  // source of children not decoded yet
+	scope []xml.Attr                 // namespace declarations in scope, set by Decoder
+
+	// stream is binary content of io.Reader fields, written after Text
+	// base64 encoded by Encoder.
+	stream io.Reader
+}
+
+// A Node is an item of mixed content: text, a child element, a comment or
+// a processing instruction.
+type Node struct {
+	Text string
+	Elem *Element // nil for text, comments and processing instructions
+
+	// Comment reports that Text is the content of a comment.
+	Comment bool
+
+	// Target, if not empty, is the target of a processing instruction
+	// whose content is in Text.
+	Target string
 }
 
 // MakeElement takes some data structure in a and its name and produces an
-// Element (or some Element tree) for it. For struct fields you can use tags
-// in the form `soap:"NAME,OPTION"`. Known options: omitempty, in.
+// Element (or some Element tree) for it. MakeElement uses conventions of
+// DefaultProfile. It panics if a can't be encoded (see Marshal).
+//
+// For struct fields you can use tags in the form `soap:"NAME,OPTION"`. Known
+// options: omitempty, in and union=T1|T2|... which declares an xsd:union of
+// XML Schema types (e.g. `soap:"Value,union=int|string"`): the value is
+// written with the first member type of its kind and Load sets interface
+// fields to the value of the first member type that parses. Fields with
+// choice=GROUP option are alternatives of an xsd:choice: exactly one of them
+// must be set (not empty) and only that one is written.
+//
+// Facet options constrain values of fields, which are checked when they are
+// written and loaded (violations are reported as *FacetError): min=N and
+// max=N (or unbounded) limit the number of items of slices, length=N,
+// minLength=N and maxLength=N limit the length of strings and []byte, and
+// pattern=RE (the last option) requires strings to match the regular
+// expression RE. Float and string fields with totalDigits=N or
+// fractionDigits=N options are written as xsd:decimal; values with more
+// fraction digits are rounded if the round option is given.
+//
+// Slices and arrays are written as SOAP-ENC:Array with SOAP-ENC:arrayType
+// giving the type and number of items, e.g. xsd:string[3] (nil slices with
+// xsi:nil, so they differ from empty ones; the omitempty option omits both)
+// and []byte as xsd:base64Binary. Fields of type io.Reader are
+// xsd:base64Binary too: their content is read and encoded when the element
+// is written, directly to the output by Encoder.Encode, so it needn't fit in
+// memory. It can be written only once. Values of type *Element (e.g.
+// fields of xsd:anyType) are embedded as copies renamed to the field name
+// and items of []*Element fields become repeated elements of the field name.
 func MakeElement(name string, a interface{}) *Element {
-	e := new(Element)
-	e.XMLName.Local = name
+	return DefaultProfile.MakeElement(name, a)
+}
 
+// MakeElement works like package level MakeElement function but uses
+// conventions of p.
+func (p *Profile) MakeElement(name string, a interface{}) *Element {
+	e, err := p.Marshal(name, a)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Marshal works like MakeElement but returns an error if a contains values
+// that can't be encoded (e.g. channels or functions).
+func Marshal(name string, a interface{}) (*Element, error) {
+	return DefaultProfile.Marshal(name, a)
+}
+
+// Marshal works like package level Marshal function but uses conventions of
+// p. The tree is built using an explicit stack and its depth is limited by
+// p.MaxDepth, so cyclic data structures result in ErrTooDeep instead of an
+// endless loop.
+func (p *Profile) Marshal(name string, a interface{}) (*Element, error) {
+	root := newElement()
+	root.XMLName.Local = name
+	if err := p.build(root, a, 1); err != nil {
+		root.Release()
+		return nil, err
+	}
+	return root, nil
+}
+
+// build encodes a into root which is at given depth of the tree.
+func (p *Profile) build(root *Element, a interface{}, depth int) error {
+	max := p.maxDepth()
+	tasks := []encodeTask{{root, a, depth}}
+	var maps []*Element
+	for len(tasks) > 0 {
+		t := tasks[len(tasks)-1]
+		tasks = tasks[:len(tasks)-1]
+		if t.depth > max {
+			return ErrTooDeep
+		}
+		var err error
+		tasks, err = p.encode(t.e, t.a, t.depth, tasks)
+		if err != nil {
+			return err
+		}
+		if isGoMap(t.a) {
+			maps = append(maps, t.e)
+		}
+	}
+	// Stable order of map items makes output reproducible. Only maps built
+	// from Go maps are sorted: *Element values are passed through as is.
+	for _, m := range maps {
+		sort.SliceStable(m.Children, func(i, j int) bool {
+			return m.Children[i].Children[0].Text <
+				m.Children[j].Children[0].Text
+		})
+	}
+	return nil
+}
+
+// isGoMap reports whether a is a Go map or a pointer to one.
+func isGoMap(a interface{}) bool {
+	v := reflect.ValueOf(a)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Map
+}
+
+// encodeTask describes a value a that should be encoded into element e at
+// given depth of the tree.
+type encodeTask struct {
+	e     *Element
+	a     interface{}
+	depth int
+}
+
+// encode sets type and content of e according to a and appends tasks for
+// child elements of e to tasks.
+func (p *Profile) encode(e *Element, a interface{}, depth int, tasks []encodeTask) ([]encodeTask, error) {
 	if a == nil {
 		e.Nil = true
-		return e
+		return tasks, nil
+	}
+	if src, ok := a.(*Element); ok {
+		if src == nil {
+			e.Nil = true
+		} else {
+			e.setContent(src)
+		}
+		return tasks, nil
+	}
+	if arr, ok := a.(Array); ok {
+		e.Type = p.encType("Array")
+		e.Nil = arr == nil
+		if arr != nil {
+			p.setArrayType(e, nil, len(arr))
+		}
+		for _, item := range arr {
+			c := newElement()
+			c.XMLName.Local = "item"
+			e.Children = append(e.Children, c)
+			tasks = append(tasks, encodeTask{c, item, depth + 1})
+		}
+		return tasks, nil
 	}
 
 	v := reflect.ValueOf(a)
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
 			e.Nil = true
-			return e
+			return tasks, nil
 		}
 		v = v.Elem()
 	}
 
 	if t, ok := v.Interface().(time.Time); ok {
-		e.Type = "xsd:dateTime"
-		e.Text = t.Format(timeFormatSOAP)
-		return e
+		e.Type = p.xsdType("dateTime")
+		e.Text = p.formatTime(t)
+		return tasks, nil
+	}
+	if q, ok := v.Interface().(QName); ok {
+		p.setQName(e, q)
+		return tasks, nil
+	}
+	if isUUIDType(v.Type()) {
+		p.setUUID(e, v)
+		return tasks, nil
+	}
+	if typ := calendarTypes[v.Type()]; typ != "" {
+		p.setCalendar(e, typ, v.Interface())
+		return tasks, nil
 	}
 
 	switch v.Kind() {
 	case reflect.String:
-		e.Type = "xsd:string"
+		e.Type = p.xsdType("string")
 		e.Text = v.String()
 
 	case reflect.Bool:
-		e.Type = "xsd:boolean"
-		if v.Bool() {
-			e.Text = "true"
-		} else {
-			e.Text = "false"
-		}
+		e.Type = p.xsdType("boolean")
+		e.Text = p.boolLiteral(v.Bool())
 
 	case reflect.Int, reflect.Int64:
-		e.Type = "xsd:long"
+		e.Type = p.xsdType("long")
 		e.Text = strconv.FormatInt(v.Int(), 10)
 
 	case reflect.Int32:
-		e.Type = "xsd:int"
+		e.Type = p.xsdType("int")
 		e.Text = strconv.FormatInt(v.Int(), 10)
 
 	case reflect.Int16:
-		e.Type = "xsd:short"
+		e.Type = p.xsdType("short")
 		e.Text = strconv.FormatInt(v.Int(), 10)
 
 	case reflect.Int8:
-		e.Type = "xsd:byte"
+		e.Type = p.xsdType("byte")
 		e.Text = strconv.FormatInt(v.Int(), 10)
 
 	case reflect.Uint, reflect.Uint64:
-		e.Type = "xsd:unsignedLong"
+		e.Type = p.xsdType("unsignedLong")
 		e.Text = strconv.FormatUint(v.Uint(), 10)
 
 	case reflect.Uint32:
-		e.Type = "xsd:unsignedInt"
+		e.Type = p.xsdType("unsignedInt")
 		e.Text = strconv.FormatUint(v.Uint(), 10)
 
 	case reflect.Uint16:
-		e.Type = "xsd:unsignedShort"
+		e.Type = p.xsdType("unsignedShort")
 		e.Text = strconv.FormatUint(v.Uint(), 10)
 
 	case reflect.Uint8:
-		e.Type = "xsd:unsignedByte"
+		e.Type = p.xsdType("unsignedByte")
 		e.Text = strconv.FormatUint(v.Uint(), 10)
 
 	case reflect.Float32:
-		e.Type = "xsd:float"
+		e.Type = p.xsdType("float")
 		e.Text = strconv.FormatFloat(v.Float(), 'e', 7, 32)
 
 	case reflect.Float64:
-		e.Type = "xsd:double"
+		e.Type = p.xsdType("double")
 		e.Text = strconv.FormatFloat(v.Float(), 'e', 16, 64)
 
 	case reflect.Struct:
-		e.Type = "SOAP-ENC:Struct"
-		t := v.Type()
-		n := t.NumField()
-		for i := 0; i < n; i++ {
-			ft := t.Field(i)
-			fv := v.Field(i)
-			if ft.PkgPath != "" {
-				continue // unexported field
+		e.Type = p.encType("Struct")
+		setStructName(e, v)
+		fs := cachedFields(v.Type())
+		err := checkChoice(fs, true, func(i int) bool {
+			return !isEmptyValue(v.Field(fs[i].index))
+		})
+		if err != nil {
+			return tasks, err
+		}
+		for _, f := range fs {
+			fv := v.Field(f.index)
+			if f.in || f.omitEmpty && isEmptyValue(fv) {
+				continue
 			}
-			name := ft.Tag.Get("soap")
-			if i := strings.IndexRune(name, ','); i != -1 {
-				opts := name[i:]
-				name = name[:i]
-				if strings.Contains(opts, ",omitempty") && isEmptyValue(fv) {
-					continue
+			if f.check != nil {
+				if err := f.check(fv); err != nil {
+					return tasks, inPath(err, e.XMLName.Local)
 				}
-				if strings.Contains(opts, ",in") {
-					continue
+			}
+			if f.typ == elementsType {
+				e.Children = appendCopies(e.Children, f.name, fv.Interface().([]*Element))
+				continue
+			}
+			c := newElement()
+			c.XMLName = xml.Name{Space: e.XMLName.Space, Local: f.name}
+			if f.typ.Kind() == reflect.Interface {
+				c.XMLName.Local = memberName(f.name, fv)
+			}
+			e.Children = append(e.Children, c)
+			if f.typ == readerType {
+				r, _ := fv.Interface().(io.Reader)
+				p.setStream(c, r)
+				continue
+			}
+			if f.digits != nil {
+				if err := f.digits.encode(p, c, fv); err != nil {
+					return tasks, inPath(err, e.XMLName.Local)
 				}
+				continue
 			}
-			if name == "-" {
+			if f.union == nil {
+				tasks = append(tasks, encodeTask{c, fv.Interface(), depth + 1})
 				continue
 			}
-			if name == "" {
-				name = ft.Name
+			var err error
+			if tasks, err = p.encode(c, fv.Interface(), depth+1, tasks); err != nil {
+				return tasks, err
 			}
-			e.Children = append(
-				e.Children,
-				MakeElement(name, fv.Interface()),
-			)
+			p.unionType(c, f.union)
 		}
 
 	case reflect.Slice, reflect.Array:
-		panic("soap: slices and arrays not implemented yet")
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			e.Type = p.xsdType("base64Binary")
+			e.Nil = v.IsNil()
+			e.Text = base64.StdEncoding.EncodeToString(v.Bytes())
+			break
+		}
+		e.Type = p.encType("Array")
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.Nil = true // unlike an empty slice
+			break
+		}
+		p.setArrayType(e, v.Type().Elem(), v.Len())
+		for i, n := 0, v.Len(); i < n; i++ {
+			c := newElement()
+			c.XMLName.Local = "item"
+			e.Children = append(e.Children, c)
+			tasks = append(tasks, encodeTask{c, v.Index(i).Interface(), depth + 1})
+		}
 
 	case reflect.Map:
-		e.Type = "ns2:Map"
+		e.Type = p.mapType()
 		if v.IsNil() {
 			e.Nil = true
 		}
 		for _, k := range v.MapKeys() {
-			item := new(Element)
+			key, val := newElement(), newElement()
+			key.XMLName.Local = "key"
+			val.XMLName.Local = "value"
+			item := newElement()
 			item.XMLName.Local = "item"
-			item.Children = []*Element{
-				MakeElement("key", k.Interface()),
-				MakeElement("value", v.MapIndex(k).Interface()),
-			}
+			item.Children = append(item.Children, key, val)
 			e.Children = append(e.Children, item)
+			tasks = append(
+				tasks,
+				encodeTask{key, k.Interface(), depth + 2},
+				encodeTask{val, v.MapIndex(k).Interface(), depth + 2},
+			)
 		}
 
 	default:
-		panic("soap: unknown kind of type: " + v.Kind().String())
+		return tasks, errors.New("soap: can't encode value of type " + v.Type().String())
 	}
-	return e
+	return tasks, nil
 }
 
 func skipNS(s string) string {
@@ -174,121 +412,90 @@ func skipNS(s string) string {
 	return s[i+1:]
 }
 
-func (e *Element) badValue(typ string) error {
-	val := e.Text
-	if e.Children != nil {
-		val = "{...}"
+// badValue returns a ValueError caused by err (if not nil).
+func (e *Element) badValue(typ string, err error) error {
+	ve := &ValueError{
+		Value:  e.Text,
+		Type:   skipNS(e.Type),
+		GoType: typ,
+		Path:   e.XMLName.Local,
+		Err:    err,
 	}
-	if typ == "" {
-		typ = "SOAP:" + skipNS(e.Type)
-	} else {
-		typ = "Go:" + typ
+	if e.hasChildren() {
+		ve.Value = "{...}"
 	}
-	return errors.New(
-		"soap: bad value '" + val + "' for type " + typ,
-	)
+	return ve
 }
 
 // Value returns SOAP element as Go data structure. It can be a simple scalar
 // value or more complicated structure that contains maps and slices.
 // Returned value is built using following data types: string, bool, int64,
 // uint64, float64, map[intreface{}]interface{}, []interface{}
+// Elements without xsi:type attribute are decoded according to registered
+// schemas (see RegisterSchema).
 func (e *Element) Value() (interface{}, error) {
-	if e.Nil {
-		return nil, nil
-	}
+	return DefaultDecoder.Value(e)
+}
 
-	switch skipNS(e.Type) {
+// scalarValue returns value of e, which is of simple SOAP type typ. It
+// returns nil value and nil error if typ isn't a simple type. Times without
+// zone are in loc.
+func (e *Element) scalarValue(typ string, loc *time.Location) (interface{}, error) {
+	switch typ {
 	case "string":
 		return e.Text, nil
 
 	case "boolean":
 		switch e.Text {
-		case "true":
+		case "true", "1":
 			return true, nil
-		case "false":
+		case "false", "0":
 			return false, nil
 		}
-		return nil, e.badValue("")
+		return nil, e.badValue("", nil)
 
 	case "long", "int", "short", "byte":
 		v, err := strconv.ParseInt(e.Text, 10, 64)
 		if err != nil {
-			return nil, e.badValue("")
+			return nil, e.badValue("", err)
 		}
 		return v, nil
 
 	case "unsignedLong", "unsignedInt", "unsignedShort", "unsignedByte":
 		v, err := strconv.ParseUint(e.Text, 10, 64)
 		if err != nil {
-			return nil, e.badValue("")
+			return nil, e.badValue("", err)
 		}
 		return v, nil
 
-	case "float", "double":
+	case "float", "double", "decimal":
 		v, err := strconv.ParseFloat(e.Text, 64)
 		if err != nil {
-			return nil, e.badValue("")
+			return nil, e.badValue("", err)
 		}
 		return v, nil
 
 	case "dateTime":
-		v, err := time.Parse(timeFormatSOAP, e.Text)
+		v, err := parseDateTime(e.Text, loc)
 		if err != nil {
-			return nil, e.badValue("")
+			return nil, e.badValue("", err)
 		}
 		return v, nil
 
-	case "Struct":
-		m := make(map[string]interface{})
-		for _, c := range e.Children {
-			v, err := c.Value()
-			if err != nil {
-				return nil, err
-			}
-			m[c.XMLName.Local] = v
-		}
-		return m, nil
+	case "QName":
+		return e.AsQName()
 
-	case "Array":
-		var a []interface{}
-		for _, c := range e.Children {
-			if c.XMLName.Local != "item" {
-				return nil, errors.New(
-					"soap: bad element '" + c.XMLName.Local + "'in array",
-				)
-			}
-			v, err := c.Value()
-			if err != nil {
-				return nil, err
-			}
-			a = append(a, v)
-		}
-		return a, nil
+	case "gYear", "gMonth", "gDay", "gMonthDay":
+		return e.calendar(typ, false)
 
-	case "Map":
-		m := make(map[interface{}]interface{})
-		for _, c := range e.Children {
-			key, val, err := c.MapItem()
-			if err != nil {
-				return nil, err
-			}
-			k, err := key.Value()
-			if err != nil {
-				return nil, err
-			}
-			v, err := val.Value()
-			if err != nil {
-				return nil, err
-			}
-			m[k] = v
-		}
-		return m, nil
+	case "base64Binary":
+		return e.AsBytes()
 	}
-	return nil, errors.New("soap: unknown type: " + e.Type)
+	return nil, nil
 }
 
 func (e *Element) MapItem() (key, val *Element, err error) {
+	e.expand()
 	if e.XMLName.Local != "item" {
 		err = errors.New(
 			"soap: element'" + e.XMLName.Local + "' isn't a map item",
@@ -297,6 +504,7 @@ func (e *Element) MapItem() (key, val *Element, err error) {
 	}
 	if len(e.Children) != 2 || e.Children[0] == nil || e.Children[1] == nil {
 		err = errors.New("soap: bad number of children in map item")
+		return
 	}
 
 	switch "key" {
@@ -326,22 +534,23 @@ func (e *Element) MapItem() (key, val *Element, err error) {
 }
 
 // Get returns an element of e (which should be Struct or Map) described by key.
-// It returns nil if there is no element for given key.
+// It returns nil if there is no element for given key. Untyped elements are
+// treated as Struct (document/literal style).
 func (e *Element) Get(key interface{}) (*Element, error) {
 	if e.Nil {
 		return nil, errors.New("soap: can't get value from nil Struct/Map")
 	}
+	e.expand()
 
 	switch skipNS(e.Type) {
-	case "Struct":
-		for _, c := range e.Children {
-			if c.XMLName.Local != key {
-				continue
-			}
-			return c, nil
+	case "Struct", "":
+		if name, ok := key.(string); ok {
+			return e.child(name), nil
 		}
+		return nil, nil
 
 	case "Map":
+		key = mapKey(key)
 		for _, c := range e.Children {
 			k, v, err := c.MapItem()
 			if err != nil {
@@ -351,17 +560,43 @@ func (e *Element) Get(key interface{}) (*Element, error) {
 			if err != nil {
 				return nil, err
 			}
-			if kv != key {
+			if !sameKey(kv, key) {
 				continue
 			}
 			return v, nil
 		}
 		return nil, nil
 	}
-	panic(fmt.Sprintf("%#v\n", e))
 	return nil, errors.New("soap: element isn't Struct nor Map")
 }
 
+// sameKey reports whether map keys a and b are equal. Unlike ==, it doesn't
+// panic on incomparable values.
+func sameKey(a, b interface{}) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) || t != nil && !t.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// mapKey converts integer and floating point keys to the types returned by
+// Value for them, so Get(1) finds an item with xsd:int key 1.
+func mapKey(k interface{}) interface{} {
+	v := reflect.ValueOf(k)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return k
+}
+
 // GetValue works like Get but returns value of element.
 func (e *Element) GetValue(key interface{}) (interface{}, error) {
 	c, err := e.Get(key)
@@ -372,10 +607,11 @@ func (e *Element) GetValue(key interface{}) (interface{}, error) {
 }
 
 func (e *Element) typeError(exp string) error {
-	return fmt.Errorf(
-		"soap: element of type '%s' but '%s' expected",
-		skipNS(e.Type), exp,
-	)
+	return &TypeError{
+		Type:     skipNS(e.Type),
+		Expected: exp,
+		Path:     e.XMLName.Local,
+	}
 }
 
 func (e *Element) Str() (string, error) {
@@ -385,9 +621,51 @@ func (e *Element) Str() (string, error) {
 	return e.Text, nil
 }
 
+// Bytes returns the value of e of xsd:base64Binary type.
+func (e *Element) Bytes() ([]byte, error) {
+	if skipNS(e.Type) != "base64Binary" {
+		return nil, e.typeError("base64Binary")
+	}
+	return e.AsBytes()
+}
+
+// AsBytes decodes the text of e as base64 regardless of the type of e. It
+// returns nil if e has xsi:nil set.
+func (e *Element) AsBytes() ([]byte, error) {
+	if e.hasChildren() {
+		return nil, e.badValue("[]byte", nil)
+	}
+	if e.Nil {
+		return nil, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(stripSpace(e.Text))
+	if err != nil {
+		return nil, e.badValue("[]byte", err)
+	}
+	return b, nil
+}
+
+// stripSpace removes white space, allowed between base64 characters, from s.
+func stripSpace(s string) string {
+	if strings.IndexAny(s, " \t\r\n") == -1 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+}
+
 func (e *Element) AsStr() string {
-	if e.Children != nil {
-		return fmt.Sprint(e.Value())
+	if e.hasChildren() {
+		v, err := e.Value()
+		if err != nil {
+			return e.Text
+		}
+		return string(appendValue(nil, v))
 	}
 	return e.Text
 }
@@ -397,50 +675,53 @@ func (e *Element) Bool() (bool, error) {
 		return false, e.typeError("boolean")
 	}
 	switch e.Text {
-	case "true":
+	case "true", "1":
 		return true, nil
-	case "false":
+	case "false", "0":
 		return false, nil
 	}
-	return false, e.badValue("")
+	return false, e.badValue("", nil)
 }
 
 func (e *Element) AsBool() (bool, error) {
-	if e.Children != nil {
-		return false, e.badValue("bool")
+	if e.hasChildren() {
+		return false, e.badValue("bool", nil)
 	}
 	if e.Nil {
 		return false, nil
 	}
 	b, err := strconv.ParseBool(e.Text)
 	if err != nil {
-		return false, e.badValue("bool")
+		return false, e.badValue("bool", err)
 	}
 	return b, nil
 }
 
-func soapIntTypeName(bits int) string {
+func soapIntTypeName(bits int) (string, error) {
 	switch bits {
 	case 64:
-		return "long"
+		return "long", nil
 	case 32:
-		return "int"
+		return "int", nil
 	case 16:
-		return "short"
+		return "short", nil
 	case 8:
-		return "byte"
+		return "byte", nil
 	}
-	panic("wrong number of bits for SOAP int")
+	return "", errors.New("soap: wrong number of bits for SOAP int")
 }
 
 func (e *Element) Int(bits int) (int64, error) {
-	t := soapIntTypeName(bits)
+	t, err := soapIntTypeName(bits)
+	if err != nil {
+		return 0, err
+	}
 	if skipNS(e.Type) != t {
 		return 0, e.typeError(t)
 	}
 	v, err := strconv.ParseInt(e.Text, 10, bits)
 	if err != nil {
-		return 0, e.badValue("")
+		return 0, e.badValue("", err)
 	}
 	return v, nil
 }
@@ -460,32 +741,35 @@ func (e *Element) Int16() (int16, error) {
 }
 
 func (e *Element) Int8() (int8, error) {
-	v, err := e.Uint(8)
+	v, err := e.Int(8)
 	return int8(v), err
 }
 
-func soapUintTypeName(bits int) string {
+func soapUintTypeName(bits int) (string, error) {
 	switch bits {
 	case 64:
-		return "unsignedLong"
+		return "unsignedLong", nil
 	case 32:
-		return "unsignedInt"
+		return "unsignedInt", nil
 	case 16:
-		return "unsignedShort"
+		return "unsignedShort", nil
 	case 8:
-		return "unsignedByte"
+		return "unsignedByte", nil
 	}
-	panic("wrong number of bits for SOAP uint")
+	return "", errors.New("soap: wrong number of bits for SOAP uint")
 }
 
 func (e *Element) Uint(bits int) (uint64, error) {
-	t := soapUintTypeName(bits)
+	t, err := soapUintTypeName(bits)
+	if err != nil {
+		return 0, err
+	}
 	if skipNS(e.Type) != t {
 		return 0, e.typeError(t)
 	}
 	v, err := strconv.ParseUint(e.Text, 10, bits)
 	if err != nil {
-		return 0, e.badValue("")
+		return 0, e.badValue("", err)
 	}
 	return v, nil
 }
@@ -509,31 +793,34 @@ func (e *Element) Uint8() (uint8, error) {
 	v, err := e.Uint(8)
 	return uint8(v), err
 }
-func goIntTypeName(bits int) string {
+func goIntTypeName(bits int) (string, error) {
 	switch bits {
 	case 64:
-		return "int64"
+		return "int64", nil
 	case 32:
-		return "int32"
+		return "int32", nil
 	case 16:
-		return "int16"
+		return "int16", nil
 	case 8:
-		return "int8"
+		return "int8", nil
 	}
-	panic("wrong number of bits for Go int")
+	return "", errors.New("soap: wrong number of bits for Go int")
 }
 
 func (e *Element) AsInt(bits int) (int64, error) {
-	t := goIntTypeName(bits)
-	if e.Children != nil {
-		return 0, e.badValue(t)
+	t, err := goIntTypeName(bits)
+	if err != nil {
+		return 0, err
+	}
+	if e.hasChildren() {
+		return 0, e.badValue(t, nil)
 	}
 	if e.Nil {
 		return 0, nil
 	}
 	v, err := strconv.ParseInt(e.Text, 10, bits)
 	if err != nil {
-		return 0, e.badValue(t)
+		return 0, e.badValue(t, err)
 	}
 	return v, nil
 }
@@ -557,31 +844,34 @@ func (e *Element) AsInt8() (int8, error) {
 	return int8(v), err
 }
 
-func goUintTypeName(bits int) string {
+func goUintTypeName(bits int) (string, error) {
 	switch bits {
 	case 64:
-		return "uint64"
+		return "uint64", nil
 	case 32:
-		return "uint32"
+		return "uint32", nil
 	case 16:
-		return "uint16"
+		return "uint16", nil
 	case 8:
-		return "uint8"
+		return "uint8", nil
 	}
-	panic("wrong number of bits for Go uint")
+	return "", errors.New("soap: wrong number of bits for Go uint")
 }
 
 func (e *Element) AsUint(bits int) (uint64, error) {
-	t := goIntTypeName(bits)
-	if e.Children != nil {
-		return 0, e.badValue(t)
+	t, err := goIntTypeName(bits)
+	if err != nil {
+		return 0, err
+	}
+	if e.hasChildren() {
+		return 0, e.badValue(t, nil)
 	}
 	if e.Nil {
 		return 0, nil
 	}
 	v, err := strconv.ParseUint(e.Text, 10, bits)
 	if err != nil {
-		return 0, e.badValue(t)
+		return 0, e.badValue(t, err)
 	}
 	return v, nil
 }
@@ -605,24 +895,27 @@ func (e *Element) AsUint8() (uint8, error) {
 	return uint8(v), err
 }
 
-func soapFloatTypeName(bits int) string {
+func soapFloatTypeName(bits int) (string, error) {
 	switch bits {
 	case 64:
-		return "double"
+		return "double", nil
 	case 32:
-		return "float"
+		return "float", nil
 	}
-	panic("wrong number of bits for SOAP float")
+	return "", errors.New("soap: wrong number of bits for SOAP float")
 }
 
 func (e *Element) Float(bits int) (float64, error) {
-	t := soapFloatTypeName(bits)
+	t, err := soapFloatTypeName(bits)
+	if err != nil {
+		return 0, err
+	}
 	if skipNS(e.Type) != t {
 		return 0, e.typeError(t)
 	}
 	v, err := strconv.ParseFloat(e.Text, bits)
 	if err != nil {
-		return 0, e.badValue("")
+		return 0, e.badValue("", err)
 	}
 	return v, nil
 }
@@ -636,27 +929,30 @@ func (e *Element) Float32() (float32, error) {
 	return float32(v), err
 }
 
-func goFloatTypeName(bits int) string {
+func goFloatTypeName(bits int) (string, error) {
 	switch bits {
 	case 64:
-		return "float64"
+		return "float64", nil
 	case 32:
-		return "float32"
+		return "float32", nil
 	}
-	panic("wrong number of bits for Go float")
+	return "", errors.New("soap: wrong number of bits for Go float")
 }
 
 func (e *Element) AsFloat(bits int) (float64, error) {
-	t := goFloatTypeName(bits)
-	if e.Children != nil {
-		return 0, e.badValue(t)
+	t, err := goFloatTypeName(bits)
+	if err != nil {
+		return 0, err
+	}
+	if e.hasChildren() {
+		return 0, e.badValue(t, nil)
 	}
 	if e.Nil {
 		return 0, nil
 	}
 	v, err := strconv.ParseFloat(e.Text, bits)
 	if err != nil {
-		return 0, e.badValue(t)
+		return 0, e.badValue(t, err)
 	}
 	return v, nil
 }
@@ -672,23 +968,23 @@ func (e *Element) AsFloat32() (float32, error) {
 
 func (e *Element) Time() (time.Time, error) {
 	if skipNS(e.Type) != "dateTime" {
-		return time.Time{}, e.typeError("float")
+		return time.Time{}, e.typeError("dateTime")
 	}
-	v, err := time.Parse(timeFormatSOAP, e.Text)
+	v, err := parseDateTime(e.Text, time.UTC)
 	if err != nil {
-		return time.Time{}, e.badValue("")
+		return time.Time{}, e.badValue("", err)
 	}
 	return v, nil
 }
 
 func (e *Element) AsTime(loc *time.Location) (time.Time, error) {
-	if e.Children != nil {
-		return time.Time{}, e.badValue("time.Time")
+	if e.hasChildren() {
+		return time.Time{}, e.badValue("time.Time", nil)
 	}
 	if e.Nil {
 		return time.Time{}, nil
 	}
-	v, err := time.Parse(timeFormatSOAP, e.Text)
+	v, err := parseDateTime(e.Text, loc)
 	if err != nil {
 		v, err = time.ParseInLocation(timeFormatSQL, e.Text, loc)
 		if err != nil {
@@ -696,7 +992,7 @@ func (e *Element) AsTime(loc *time.Location) (time.Time, error) {
 			if err != nil {
 				v, err = time.ParseInLocation(timeFormatSQL[:10], e.Text, loc)
 				if err != nil {
-					return time.Time{}, e.badValue("time.Time")
+					return time.Time{}, e.badValue("time.Time", err)
 				}
 			}
 		}
@@ -704,174 +1000,57 @@ func (e *Element) AsTime(loc *time.Location) (time.Time, error) {
 	return v, nil
 }
 
-var timeType = reflect.TypeOf(time.Time{})
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	elementType  = reflect.TypeOf((*Element)(nil))
+	elementsType = reflect.TypeOf([]*Element(nil))
+	readerType   = reflect.TypeOf((*io.Reader)(nil)).Elem()
+)
+
+// setStream makes e an xsd:base64Binary element with content read from
+// io.Reader r when e is written.
+func (p *Profile) setStream(e *Element, r io.Reader) {
+	e.Type = p.xsdType("base64Binary")
+	if r == nil {
+		e.Nil = true
+	}
+	e.stream = r
+}
 
 // LoadStruct load structure pointed by sp. If strict==true field types should
-// match.
+// match. Types of untyped fields are taken from registered schemas (see
+// RegisterSchema). LoadStruct(sp, true) is Load(sp, Strict) and
+// LoadStruct(sp, false) is Load(sp, Lenient).
 func (e *Element) LoadStruct(sp interface{}, strict bool) error {
-	p := reflect.ValueOf(sp)
-	if p.Kind() != reflect.Ptr || p.Type().Elem().Kind() != reflect.Struct {
-		return errors.New("soap: argument should be a pointer to the struct")
-	}
-	s := p.Elem()
-	t := s.Type()
-	n := s.NumField()
-	for i := 0; i < n; i++ {
-		ft := t.Field(i)
-		fv := s.Field(i)
-		if ft.PkgPath != "" {
-			continue // unexported field
-		}
-		name := ft.Tag.Get("soap")
-		if i := strings.IndexRune(name, ','); i != -1 {
-			name = name[:i]
-		}
-		if name == "-" {
-			continue
-		}
-		if name == "" {
-			name = ft.Name
-		}
-		item, err := e.Get(name)
-		if err != nil {
-			return err
-		}
-		if item == nil {
-			if strict {
-				return fmt.Errorf("soap: there is no field of name '%s'", name)
-			}
-			// Clear this field
-			fv.Set(reflect.Zero(ft.Type))
-			continue
-		}
-		var (
-			i int64
-			u uint64
-			f float64
-		)
-		switch fv.Kind() {
-		case reflect.String:
-			var s string
-			if strict {
-				s, err = item.Str()
-			} else {
-				s = item.AsStr()
-
-			}
-			fv.SetString(s)
-
-		case reflect.Bool:
-			var b bool
-			if strict {
-				b, err = item.Bool()
-			} else {
-				b, err = item.AsBool()
-			}
-			fv.SetBool(b)
-
-		case reflect.Int64:
-			if strict {
-				i, err = item.Int(64)
-			} else {
-				i, err = item.AsInt(64)
-			}
-			fv.SetInt(i)
-
-		case reflect.Int32:
-			if strict {
-				i, err = item.Int(32)
-			} else {
-				i, err = item.AsInt(32)
-			}
-			fv.SetInt(i)
-
-		case reflect.Int16:
-			if strict {
-				i, err = item.Int(16)
-			} else {
-				i, err = item.AsInt(16)
-			}
-			fv.SetInt(i)
-
-		case reflect.Int8:
-			if strict {
-				i, err = item.Int(8)
-			} else {
-				i, err = item.AsInt(8)
-			}
-			fv.SetInt(i)
-
-		case reflect.Uint64:
-			if strict {
-				u, err = item.Uint(64)
-			} else {
-				u, err = item.AsUint(64)
-
-			}
-			fv.SetUint(u)
-
-		case reflect.Uint32:
-			if strict {
-				u, err = item.Uint(32)
-			} else {
-				u, err = item.AsUint(32)
-
-			}
-			fv.SetUint(u)
-
-		case reflect.Uint16:
-			if strict {
-				u, err = item.Uint(16)
-			} else {
-				u, err = item.AsUint(16)
-
-			}
-			fv.SetUint(u)
-
-		case reflect.Uint8:
-			if strict {
-				u, err = item.Uint(8)
-			} else {
-				u, err = item.AsUint(8)
-
-			}
-			fv.SetUint(u)
-
-		case reflect.Float64:
-			if strict {
-				f, err = item.Float(64)
-			} else {
-				f, err = item.AsFloat(64)
-			}
-			fv.SetFloat(f)
-
-		case reflect.Float32:
-			if strict {
-				f, err = item.Float(64)
-			} else {
-				f, err = item.AsFloat(64)
-			}
-			fv.SetFloat(f)
-
-		default:
-			if ft.Type == timeType {
-				var t time.Time
-				if strict {
-					t, err = item.Time()
-				} else {
-					t, err = item.AsTime(time.Local)
-
-				}
-				fv.Set(reflect.ValueOf(t))
-			} else {
-				err = fmt.Errorf("soap: unsupported field type %s", ft.Type)
-			}
-		}
-		if err != nil {
-			return err
-		}
+	if strict {
+		return e.Load(sp, Strict)
 	}
-	return nil
+	return e.Load(sp, Lenient)
+}
+
+// Load loads the structure pointed by sp, converting values according to
+// mode. It uses settings of DefaultDecoder.
+//
+// Fields of type *Element are set to copies of their elements and fields of
+// type []*Element to copies of all elements of the field name, so they stay
+// valid after e is released.
+//
+// Pointer fields distinguish the three states of optional elements, as
+// MakeElement does: xsi:nil elements set them to nil, other elements (even
+// empty ones) to a pointer to the value, and absent elements leave them
+// unchanged if the field has the omitempty option or mode is Lenient. Slice
+// fields work the same way: xsi:nil arrays set them to nil and empty arrays
+// to empty slices. Map fields, e.g. map[string]T or
+// map[interface{}]interface{}, work like slices and are loaded from items of
+// Apache SOAP Maps written by MakeElement. []byte fields hold
+// xsd:base64Binary values. In Lenient mode absent elements leave fields of
+// any type unchanged.
+//
+// Fields of a choice group that are absent are set to zero values. It is an
+// error if more than one alternative is present or, unless mode is Lenient,
+// if none is.
+func (e *Element) Load(sp interface{}, mode DecodeMode) error {
+	return DefaultDecoder.Load(e, sp, mode)
 }
 
 func isEmptyValue(v reflect.Value) bool {
@@ -0,0 +1,114 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func mapItem(key string) *Element {
+	item := &Element{XMLName: xml.Name{Local: "item"}}
+	if key != "" {
+		item.Children = []*Element{
+			{XMLName: xml.Name{Local: "key"}, Text: key},
+			{XMLName: xml.Name{Local: "value"}},
+		}
+	}
+	return item
+}
+
+func TestNilSlices(t *testing.T) {
+	type S struct {
+		O []int  `soap:",omitempty"`
+		B []byte `soap:",omitempty"`
+		N []int
+		E []int
+	}
+	e, err := Marshal("S", S{O: []int{}, B: []byte{}, E: []int{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Children) != 2 {
+		t.Fatalf("%d children, want N and E", len(e.Children))
+	}
+	if n, c := e.Children[0], e.Children[1]; !n.Nil || c.Nil {
+		t.Errorf("N nil: %t, E nil: %t", n.Nil, c.Nil)
+	}
+	for _, mode := range []DecodeMode{Strict, Coerce, Lenient} {
+		var s S
+		if err := e.Load(&s, mode); err != nil {
+			t.Fatal(err)
+		}
+		if s.N != nil || s.E == nil || len(s.E) != 0 {
+			t.Errorf("mode %d: N: %#v, E: %#v", mode, s.N, s.E)
+		}
+	}
+}
+
+func TestMapItemsOrder(t *testing.T) {
+	// Passthrough content is kept as is, even with items without children.
+	m := &Element{Type: "ns2:Map", Children: []*Element{
+		mapItem(""), mapItem("b"), mapItem("a"),
+	}}
+	type S struct {
+		M *Element
+		G map[string]int64
+	}
+	e, err := Marshal("S", S{m, map[string]int64{"z": 1, "y": 2, "x": 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Children[0].Children[1].Children[0].Text != "b" {
+		t.Error("passthrough map reordered")
+	}
+	for i, k := range []string{"x", "y", "z"} {
+		if got := e.Children[1].Children[i].Children[0].Text; got != k {
+			t.Errorf("item %d of Go map has key %s, want %s", i, got, k)
+		}
+	}
+	if _, err := Marshal("M", m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// benchRecord is an item of representative rpc/encoded payloads.
+type benchRecord struct {
+	ID      int64
+	Name    string
+	Active  bool
+	Balance float64
+	Tags    []string
+}
+
+func benchRecords(n int) []benchRecord {
+	rs := make([]benchRecord, n)
+	for i := range rs {
+		rs[i] = benchRecord{
+			ID:      int64(i),
+			Name:    "customer name",
+			Active:  i%2 == 0,
+			Balance: float64(i) * 1.25,
+			Tags:    []string{"a", "b"},
+		}
+	}
+	return rs
+}
+
+func BenchmarkMakeElement(b *testing.B) {
+	rs := benchRecords(100)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MakeElement("records", rs).Release()
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	type resp struct{ Records []benchRecord }
+	e := MakeElement("resp", resp{benchRecords(100)})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var r resp
+		if err := e.Load(&r, Strict); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
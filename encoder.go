@@ -0,0 +1,508 @@
+package soap
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Namespaces of SOAP 1.1 envelope and encoding.
+const (
+	NamespaceSOAPEnv = "http://schemas.xmlsoap.org/soap/envelope/"
+	NamespaceSOAPEnc = "http://schemas.xmlsoap.org/soap/encoding/"
+)
+
+// An Encoder writes Element trees as XML. Output is produced directly into
+// byte slices (taken from a pool when writing to an io.Writer), without
+// encoding/xml reflection. The zero value can be used for Append* methods.
+type Encoder struct {
+	// Profile determines the prefixes declared in envelopes. If nil,
+	// DefaultProfile is used.
+	Profile *Profile
+
+	// Snapshot makes Encode, EncodeEnvelope and Append methods write trees
+	// in a stable form for golden tests of generated requests: with
+	// attributes sorted, prefixes of element and attribute names chosen by
+	// the encoder regardless of the source and no white space between
+	// elements (except in xml:space="preserve" scopes). Order of elements
+	// and empty elements are kept. The written trees aren't changed.
+	Snapshot bool
+
+	// Mask lists local names of elements and attributes whose values are
+	// replaced by *** in snapshots, e.g. volatile timestamps and MessageID.
+	Mask []string
+
+	w io.Writer
+}
+
+// NewEncoder returns an Encoder that writes to w, configured by opts.
+func NewEncoder(w io.Writer, opts ...Option) *Encoder {
+	enc := &Encoder{w: w}
+	for _, o := range opts {
+		if o.enc != nil {
+			o.enc(enc)
+		}
+	}
+	return enc
+}
+
+var bufPool = sync.Pool{
+	New: func() interface{} { return new([]byte) },
+}
+
+func (enc *Encoder) write(f func(b []byte) []byte) error {
+	bp := bufPool.Get().(*[]byte)
+	*bp = f((*bp)[:0])
+	_, err := enc.w.Write(*bp)
+	bufPool.Put(bp)
+	return err
+}
+
+// Encode writes e to the underlying writer. Binary content read from
+// io.Reader fields is base64 encoded directly to the writer.
+func (enc *Encoder) Encode(e *Element) error {
+	s := &streamer{w: enc.w}
+	err := enc.write(func(b []byte) []byte {
+		return s.done(enc.appendTree(b, e, "", s))
+	})
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
+
+// EncodeEnvelope writes a SOAP envelope to the underlying writer (see
+// AppendEnvelope). Binary content is written as by Encode.
+func (enc *Encoder) EncodeEnvelope(header []*Element, body ...*Element) error {
+	s := &streamer{w: enc.w}
+	err := enc.write(func(b []byte) []byte {
+		return s.done(enc.appendEnvelope(b, header, body, s))
+	})
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
+
+// flushSize is the amount of buffered output after which EncodeArray writes
+// to the underlying writer.
+const flushSize = 32 << 10
+
+// EncodeArray writes an array element of given name whose items are yielded
+// one by one by items, e.g.
+//
+//	enc.EncodeArray("records", func(yield func(interface{}) bool) {
+//		for rows.Next() {
+//			...
+//			if !yield(rec) {
+//				return
+//			}
+//		}
+//	})
+//
+// Every item is made by Profile of enc, written and released before the next
+// one is requested, so arbitrarily long arrays are encoded in constant memory.
+// The array element declares the prefixes used in xsi:type values, so it can
+// be written on its own, and its SOAP-ENC:arrayType is xsd:ur-type[], as the
+// type and number of items aren't known in advance.
+func (enc *Encoder) EncodeArray(name string, items func(yield func(interface{}) bool)) error {
+	p := enc.profile()
+	bp := bufPool.Get().(*[]byte)
+	defer bufPool.Put(bp)
+	b := append((*bp)[:0], '<')
+	b = append(b, name...)
+	b = append(b, ` xmlns:xsi="`+NamespaceXSI+`"`...)
+	if typ := p.encType("Array"); typ != "" {
+		encPrefix := orDefault(p.EncPrefix, "SOAP-ENC")
+		b = appendNSDecl(b, orDefault(p.XSDPrefix, "xsd"), NamespaceXSD)
+		b = appendNSDecl(b, encPrefix, NamespaceSOAPEnc)
+		b = appendNSDecl(b, orDefault(p.MapPrefix, "ns2"), NamespaceApacheSOAP)
+		b = append(b, ` xsi:type="`...)
+		b = append(b, typ...)
+		b = append(b, `" `...)
+		b = append(b, encPrefix...)
+		b = append(b, `:arrayType="`...)
+		b = append(b, p.xsdType("ur-type")...)
+		b = append(b, `[]"`...)
+	}
+	b = append(b, '>')
+	var err error
+	s := &streamer{w: enc.w}
+	items(func(a interface{}) bool {
+		if err != nil {
+			return false // items ignored the previous false
+		}
+		var e *Element
+		if e, err = p.Marshal("item", a); err != nil {
+			return false
+		}
+		b = appendTree(b, e, "xsi", s)
+		e.Release()
+		if err = s.err; err != nil {
+			return false
+		}
+		if len(b) >= flushSize {
+			_, err = enc.w.Write(b)
+			b = b[:0]
+		}
+		return err == nil
+	})
+	*bp = b
+	if err != nil {
+		return err
+	}
+	b = append(b, "</"...)
+	b = append(b, name...)
+	b = append(b, '>')
+	*bp = b
+	_, err = enc.w.Write(b)
+	return err
+}
+
+func (enc *Encoder) profile() *Profile {
+	if enc.Profile == nil {
+		return DefaultProfile
+	}
+	return enc.Profile
+}
+
+// AppendElement appends XML representation of e to dst and returns the
+// extended buffer. Binary content of io.Reader fields is read into memory
+// (up to the first read error, which Append methods can't report).
+func (enc *Encoder) AppendElement(dst []byte, e *Element) []byte {
+	return enc.appendTree(dst, e, "", nil)
+}
+
+// AppendEnvelope appends a SOAP 1.1 envelope to dst and returns the extended
+// buffer. The envelope declares the prefixes used in xsi:type values by
+// Profile of enc. Header is omitted if there are no header elements.
+func (enc *Encoder) AppendEnvelope(dst []byte, header []*Element, body ...*Element) []byte {
+	return enc.appendEnvelope(dst, header, body, nil)
+}
+
+func (enc *Encoder) appendEnvelope(dst []byte, header, body []*Element, s *streamer) []byte {
+	p := enc.profile()
+	dst = append(dst, `<SOAP-ENV:Envelope xmlns:SOAP-ENV="`+NamespaceSOAPEnv+
+		`" xmlns:xsi="`+NamespaceXSI+`"`...)
+	dst = appendNSDecl(dst, orDefault(p.XSDPrefix, "xsd"), NamespaceXSD)
+	dst = appendNSDecl(dst, orDefault(p.EncPrefix, "SOAP-ENC"), NamespaceSOAPEnc)
+	dst = appendNSDecl(dst, orDefault(p.MapPrefix, "ns2"), NamespaceApacheSOAP)
+	dst = append(dst, '>')
+	if len(header) != 0 {
+		dst = append(dst, "<SOAP-ENV:Header>"...)
+		for _, h := range header {
+			dst = enc.appendTree(dst, h, "xsi", s)
+		}
+		dst = append(dst, "</SOAP-ENV:Header>"...)
+	}
+	dst = append(dst, "<SOAP-ENV:Body>"...)
+	for _, e := range body {
+		dst = enc.appendTree(dst, e, "xsi", s)
+	}
+	return append(dst, "</SOAP-ENV:Body></SOAP-ENV:Envelope>"...)
+}
+
+func appendNSDecl(b []byte, prefix, ns string) []byte {
+	if prefix == "SOAP-ENV" || prefix == "xsi" {
+		return b // already declared
+	}
+	b = append(b, " xmlns:"...)
+	b = append(b, prefix...)
+	b = append(b, `="`...)
+	b = append(b, ns...)
+	return append(b, '"')
+}
+
+// encodeFrame is an element whose children are being written.
+type encodeFrame struct {
+	e      *Element
+	i      int        // next child (or Content node) to write
+	prefix string     // prefix of the element name
+	ns     string     // default namespace in scope
+	scope  []xml.Attr // namespace declarations in scope
+	xsi    string     // prefix bound to NamespaceXSI or ""
+}
+
+// appendTree appends XML of e to b. xsi is the prefix bound to NamespaceXSI
+// in scope of e ("" if none). Binary content is written by s.
+func appendTree(b []byte, e *Element, xsi string, s *streamer) []byte {
+	var (
+		stack []encodeFrame
+		ns    string
+		scope []xml.Attr
+	)
+	for {
+		if e != nil {
+			e.expand()
+			// Declarations seen when e was decoded are reproduced, so
+			// are prefixes bound by them.
+			scope = inScope(scope, xml.StartElement{Attr: e.NS})
+			for _, a := range e.NS {
+				if a.Name.Space != "xmlns" {
+					ns = a.Value
+				} else if a.Value == NamespaceXSI {
+					xsi = a.Name.Local
+				}
+			}
+			prefix := ""
+			if e.XMLName.Space != ns {
+				prefix = prefixOf(scope, e.XMLName.Space)
+			}
+			b = append(b, '<')
+			if prefix != "" {
+				b = append(b, prefix...)
+				b = append(b, ':')
+			}
+			b = append(b, e.XMLName.Local...)
+			for _, a := range e.NS {
+				b = append(b, " xmlns"...)
+				if a.Name.Space == "xmlns" {
+					b = append(b, ':')
+					b = append(b, a.Name.Local...)
+				}
+				b = append(b, `="`...)
+				b = appendEscaped(b, a.Value, true)
+				b = append(b, '"')
+			}
+			if e.XMLName.Space != ns && prefix == "" {
+				ns = e.XMLName.Space
+				b = append(b, ` xmlns="`...)
+				b = appendEscaped(b, ns, true)
+				b = append(b, '"')
+			}
+			if xsi == "" && (e.Type != "" || e.Nil || e.xsiAttrs()) {
+				xsi = "xsi"
+				b = append(b, ` xmlns:xsi="`+NamespaceXSI+`"`...)
+			}
+			if e.Type != "" {
+				b = appendAttrName(b, xsi, "type")
+				b = appendEscaped(b, e.Type, true)
+				b = append(b, '"')
+			}
+			if e.Nil {
+				b = appendAttrName(b, xsi, "nil")
+				b = append(b, `true"`...)
+			}
+			b = appendAttrs(b, e.Attrs, scope, xsi)
+			if e.Text == "" && len(e.Children) == 0 && len(e.Content) == 0 && e.stream == nil {
+				b = append(b, "/>"...)
+			} else {
+				b = append(b, '>')
+				if len(e.Content) == 0 {
+					b = appendEscaped(b, e.Text, false)
+				}
+				if e.stream != nil {
+					b = s.stream(b, e.stream)
+				}
+				stack = append(stack, encodeFrame{e, 0, prefix, ns, scope, xsi})
+			}
+		}
+		// Find the next child to write, closing finished elements.
+		e = nil
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if content := f.e.Content; len(content) != 0 {
+				for ; f.i < len(content) && content[f.i].Elem == nil; f.i++ {
+					b = appendNode(b, content[f.i])
+				}
+				if f.i < len(content) {
+					e = content[f.i].Elem
+					f.i++
+				}
+			} else if f.i < len(f.e.Children) {
+				e = f.e.Children[f.i]
+				f.i++
+			}
+			if e != nil {
+				ns, scope, xsi = f.ns, f.scope, f.xsi
+				break
+			}
+			b = append(b, "</"...)
+			if f.prefix != "" {
+				b = append(b, f.prefix...)
+				b = append(b, ':')
+			}
+			b = append(b, f.e.XMLName.Local...)
+			b = append(b, '>')
+			stack = stack[:len(stack)-1]
+		}
+		if e == nil && len(stack) == 0 {
+			return b
+		}
+	}
+}
+
+// A streamer writes binary content of elements base64 encoded directly to w,
+// after the output buffered so far. The nil streamer appends it to the
+// buffer instead.
+type streamer struct {
+	w   io.Writer
+	err error
+}
+
+// stream writes content read from r after b and returns the buffer to be
+// used for further output.
+func (s *streamer) stream(b []byte, r io.Reader) []byte {
+	if s == nil {
+		data, _ := io.ReadAll(r)
+		n := len(b)
+		b = append(b, make([]byte, base64.StdEncoding.EncodedLen(len(data)))...)
+		base64.StdEncoding.Encode(b[n:], data)
+		return b
+	}
+	if s.err != nil {
+		return b
+	}
+	if _, s.err = s.w.Write(b); s.err != nil {
+		return b[:0]
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, s.w)
+	if _, s.err = io.Copy(enc, r); s.err == nil {
+		s.err = enc.Close()
+	}
+	return b[:0]
+}
+
+// done returns b or, if streaming failed, nothing more to write.
+func (s *streamer) done(b []byte) []byte {
+	if s.err != nil {
+		return b[:0]
+	}
+	return b
+}
+
+// appendNode appends n, which isn't an element, to b.
+func appendNode(b []byte, n Node) []byte {
+	switch {
+	case n.Comment:
+		b = append(b, "<!--"...)
+		b = append(b, n.Text...)
+		return append(b, "-->"...)
+	case n.Target != "":
+		b = append(b, "<?"...)
+		b = append(b, n.Target...)
+		if n.Text != "" {
+			b = append(b, ' ')
+			b = append(b, n.Text...)
+		}
+		return append(b, "?>"...)
+	}
+	return appendEscaped(b, n.Text, false)
+}
+
+// prefixOf returns the prefix bound to namespace space in scope or "".
+func prefixOf(scope []xml.Attr, space string) string {
+	for i := len(scope) - 1; i >= 0; i-- {
+		if a := scope[i]; a.Name.Space == "xmlns" && a.Value == space {
+			return a.Name.Local
+		}
+	}
+	return ""
+}
+
+func appendAttrName(b []byte, prefix, local string) []byte {
+	b = append(b, ' ')
+	b = append(b, prefix...)
+	b = append(b, ':')
+	b = append(b, local...)
+	return append(b, `="`...)
+}
+
+// NamespaceXML is the namespace bound to the xml prefix (xml:lang,
+// xml:space).
+const NamespaceXML = "http://www.w3.org/XML/1998/namespace"
+
+func (e *Element) xsiAttrs() bool {
+	for _, a := range e.Attrs {
+		if a.Name.Space == NamespaceXSI {
+			return true
+		}
+	}
+	return false
+}
+
+// appendAttrs appends attrs to the start tag in b. Prefixes bound in scope
+// are used for namespaced attributes. Prefixes of other namespaces are
+// declared in the same tag.
+func appendAttrs(b []byte, attrs []xml.Attr, scope []xml.Attr, xsi string) []byte {
+	var spaces []string // spaces[i] is bound to prefix "a<i>"
+	for _, a := range attrs {
+		b = append(b, ' ')
+		switch a.Name.Space {
+		case "":
+		case NamespaceXSI:
+			b = append(b, xsi...)
+			b = append(b, ':')
+		case NamespaceXML:
+			b = append(b, "xml:"...)
+		default:
+			if p := prefixOf(scope, a.Name.Space); p != "" {
+				b = append(b, p...)
+				b = append(b, ':')
+				break
+			}
+			i := 0
+			for i < len(spaces) && spaces[i] != a.Name.Space {
+				i++
+			}
+			if i == len(spaces) {
+				spaces = append(spaces, a.Name.Space)
+				b = append(b, "xmlns:a"...)
+				b = strconv.AppendInt(b, int64(i), 10)
+				b = append(b, `="`...)
+				b = appendEscaped(b, a.Name.Space, true)
+				b = append(b, `" `...)
+			}
+			b = append(b, 'a')
+			b = strconv.AppendInt(b, int64(i), 10)
+			b = append(b, ':')
+		}
+		b = append(b, a.Name.Local...)
+		b = append(b, `="`...)
+		b = appendEscaped(b, a.Value, true)
+		b = append(b, '"')
+	}
+	return b
+}
+
+// appendEscaped appends s to b, escaping characters that have special
+// meaning in XML text (or in attribute values if attr is true).
+func appendEscaped(b []byte, s string, attr bool) []byte {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		var esc string
+		switch s[i] {
+		case '&':
+			esc = "&amp;"
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '\r':
+			esc = "&#xD;"
+		case '"':
+			if !attr {
+				continue
+			}
+			esc = "&quot;"
+		case '\n':
+			if !attr {
+				continue
+			}
+			esc = "&#xA;"
+		case '\t':
+			if !attr {
+				continue
+			}
+			esc = "&#x9;"
+		default:
+			continue
+		}
+		b = append(b, s[last:i]...)
+		b = append(b, esc...)
+		last = i + 1
+	}
+	return append(b, s[last:]...)
+}
@@ -0,0 +1,79 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncodeArrayNamespaces(t *testing.T) {
+	type rec struct {
+		N int64
+		M map[string]string
+	}
+	var b bytes.Buffer
+	err := NewEncoder(&b).EncodeArray("recs", func(yield func(interface{}) bool) {
+		for i := 0; i < 3; i++ {
+			if !yield(rec{int64(i), map[string]string{"k": "v"}}) {
+				return
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every prefix used in names and xsi:type values must be declared.
+	d := xml.NewDecoder(&b)
+	var scopes []map[string]bool
+	var arrayType string
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			scope := map[string]bool{"": true}
+			if len(scopes) != 0 {
+				for p := range scopes[len(scopes)-1] {
+					scope[p] = true
+				}
+			}
+			for _, a := range tok.Attr {
+				if a.Name.Space == "xmlns" {
+					scope[a.Name.Local] = true
+				}
+			}
+			scopes = append(scopes, scope)
+			used := []string{tok.Name.Space}
+			for _, a := range tok.Attr {
+				if a.Name.Space != "xmlns" {
+					used = append(used, a.Name.Space)
+				}
+				if a.Name.Local == "type" {
+					if i := strings.IndexByte(a.Value, ':'); i != -1 {
+						used = append(used, a.Value[:i])
+					}
+				}
+				if a.Name.Local == "arrayType" {
+					arrayType = a.Value
+				}
+			}
+			for _, p := range used {
+				if !scope[p] {
+					t.Errorf("undeclared prefix %s in %s", p, tok.Name.Local)
+				}
+			}
+		case xml.EndElement:
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+	if arrayType != "xsd:ur-type[]" {
+		t.Errorf("arrayType %q", arrayType)
+	}
+}
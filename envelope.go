@@ -1,7 +1,8 @@
 package soap
 
 import (
-	"fmt"
+	"strings"
+	"sync"
 )
 
 type Fault struct {
@@ -12,8 +13,79 @@ type Fault struct {
 }
 
 func (f *Fault) Error() string {
-	return fmt.Sprintf(
-		"soap: fault %s: %s: %s: %s",
-		f.Code, f.String, f.Actor, f.Detail,
-	)
+	return "soap: fault " + f.Code + ": " + f.String + ": " + f.Actor +
+		": " + f.Detail
+}
+
+// class returns the standard fault code of f without prefix and dotted
+// subcodes (e.g. Client for soap:Client.Authentication). SOAP 1.2 codes
+// Sender and Receiver are returned as Client and Server.
+func (f *Fault) class() string {
+	c := skipNS(strings.TrimSpace(f.Code))
+	if i := strings.IndexByte(c, '.'); i != -1 {
+		c = c[:i]
+	}
+	switch c {
+	case "Sender":
+		return "Client"
+	case "Receiver":
+		return "Server"
+	}
+	return c
+}
+
+// IsClientError reports whether f was caused by the request (Client or
+// Sender fault code), so repeating the same request won't help.
+func (f *Fault) IsClientError() bool {
+	return f.class() == "Client"
+}
+
+// IsServerError reports whether f was caused by processing of the request by
+// the server (Server or Receiver fault code).
+func (f *Fault) IsServerError() bool {
+	return f.class() == "Server"
+}
+
+// IsMustUnderstand reports whether f is a MustUnderstand fault: the server
+// didn't understand a header with mustUnderstand set.
+func (f *Fault) IsMustUnderstand() bool {
+	return f.class() == "MustUnderstand"
+}
+
+// IsVersionMismatch reports whether f is a VersionMismatch fault: the server
+// doesn't support the namespace of the envelope.
+func (f *Fault) IsVersionMismatch() bool {
+	return f.class() == "VersionMismatch"
+}
+
+var transientFaults struct {
+	sync.RWMutex
+	markers []string
+}
+
+// RegisterTransientFault makes faults whose code (without prefix) or detail
+// contains marker (e.g. "ServerBusy") transient, even if they are client
+// faults, as some services throttle clients that way.
+func RegisterTransientFault(marker string) {
+	transientFaults.Lock()
+	transientFaults.markers = append(transientFaults.markers, marker)
+	transientFaults.Unlock()
+}
+
+// Transient reports whether the request that caused f can succeed if
+// retried: server faults and faults registered by RegisterTransientFault
+// are transient, others are permanent.
+func (f *Fault) Transient() bool {
+	if f.IsServerError() {
+		return true
+	}
+	code := skipNS(f.Code)
+	transientFaults.RLock()
+	defer transientFaults.RUnlock()
+	for _, m := range transientFaults.markers {
+		if strings.Contains(code, m) || strings.Contains(f.Detail, m) {
+			return true
+		}
+	}
+	return false
 }
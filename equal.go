@@ -0,0 +1,120 @@
+package soap
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// EqualOption relaxes the comparison made by Equal.
+type EqualOption int
+
+const (
+	// IgnoreOrder makes Equal match children regardless of their order.
+	IgnoreOrder EqualOption = 1 << iota
+
+	// IgnoreTypes makes Equal ignore xsi:type attributes.
+	IgnoreTypes
+
+	// NumericValues makes Equal compare texts that are both numbers by
+	// their values, so 1.0 equals 1.
+	NumericValues
+)
+
+// Equal reports whether trees a and b are equal. Elements are equal if they
+// have the same name, xsi:type (without prefix), xsi:nil, attributes (in any
+// order), text and equal children. Text of elements with children is
+// compared with surrounding white space trimmed, so indentation doesn't
+// matter. Namespace declarations, comments and processing instructions are
+// ignored.
+func Equal(a, b *Element, opts ...EqualOption) bool {
+	var o EqualOption
+	for _, opt := range opts {
+		o |= opt
+	}
+	return equal(a, b, o)
+}
+
+func equal(a, b *Element, o EqualOption) bool {
+	type pair struct{ a, b *Element }
+	stack := []pair{{a, b}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if p.a == nil || p.b == nil {
+			if p.a != p.b {
+				return false
+			}
+			continue
+		}
+		if !p.a.equalNode(p.b, o) {
+			return false
+		}
+		ac, bc := p.a.Children, p.b.Children
+		if len(ac) != len(bc) {
+			return false
+		}
+		if o&IgnoreOrder == 0 {
+			for i := range ac {
+				stack = append(stack, pair{ac[i], bc[i]})
+			}
+			continue
+		}
+		used := make([]bool, len(bc))
+	match:
+		for _, c := range ac {
+			for i, d := range bc {
+				if !used[i] && equal(c, d, o) {
+					used[i] = true
+					continue match
+				}
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// equalNode compares e and f without their children. It expands both.
+func (e *Element) equalNode(f *Element, o EqualOption) bool {
+	e.expand()
+	f.expand()
+	if e.XMLName != f.XMLName || e.Nil != f.Nil {
+		return false
+	}
+	if o&IgnoreTypes == 0 && skipNS(e.Type) != skipNS(f.Type) {
+		return false
+	}
+	if !equalAttrs(e.Attrs, f.Attrs) {
+		return false
+	}
+	et, ft := e.Text, f.Text
+	if len(e.Children) != 0 || len(f.Children) != 0 {
+		et, ft = strings.TrimSpace(et), strings.TrimSpace(ft)
+	}
+	if et == ft {
+		return true
+	}
+	if o&NumericValues != 0 {
+		x, err1 := strconv.ParseFloat(et, 64)
+		y, err2 := strconv.ParseFloat(ft, 64)
+		return err1 == nil && err2 == nil && x == y
+	}
+	return false
+}
+
+func equalAttrs(a, b []xml.Attr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+next:
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				continue next
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package soap
+
+import (
+	"errors"
+	"strconv"
+)
+
+// A ValueError describes an element value that can't be converted to the
+// requested type.
+type ValueError struct {
+	Value  string // text of the element, "{...}" if it has children
+	Type   string // SOAP type of the element (without namespace prefix)
+	GoType string // Go type the value was converted to, if any
+	Path   string // names of the element and its ancestors, e.g. "Req/Count"
+	Err    error  // underlying parse error, if any
+}
+
+func (e *ValueError) Error() string {
+	typ := "SOAP:" + e.Type
+	if e.GoType != "" {
+		typ = "Go:" + e.GoType
+	}
+	s := "soap: bad value '" + e.Value + "' for type " + typ + atPath(e.Path)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *ValueError) Unwrap() error {
+	return e.Err
+}
+
+// A TypeError is returned by type-checked accessors (Str, Int, Float, ...)
+// when the element has a different SOAP type.
+type TypeError struct {
+	Type     string // SOAP type of the element (without namespace prefix)
+	Expected string // expected SOAP type
+	Path     string // names of the element and its ancestors, e.g. "Req/Count"
+}
+
+func (e *TypeError) Error() string {
+	return "soap: element" + atPath(e.Path) + " of type '" + e.Type +
+		"' but '" + e.Expected + "' expected"
+}
+
+// A FacetError describes a value that violates a constraint given by tag
+// options of its struct field (e.g. min and max).
+type FacetError struct {
+	Facet string // name of the option
+	Limit string // value of the option
+	Value string // the value or its size, e.g. "3 items"
+	Path  string // names of the element and its ancestors, e.g. "Req/Items"
+}
+
+func (e *FacetError) Error() string {
+	return "soap: value '" + e.Value + "'" + atPath(e.Path) + " violates " +
+		e.Facet + "=" + e.Limit
+}
+
+func atPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " at " + path
+}
+
+// inPath prefixes the path of err (if it is *ValueError, *TypeError or
+// *FacetError) with the name of an enclosing element.
+func inPath(err error, parent string) error {
+	switch e := err.(type) {
+	case *ValueError:
+		e.Path = parent + "/" + e.Path
+	case *TypeError:
+		e.Path = parent + "/" + e.Path
+	case *FacetError:
+		e.Path = parent + "/" + e.Path
+	}
+	return err
+}
+
+// A BudgetError is returned by Decoder when a decoded tree exceeds
+// Decoder.MaxBytes.
+type BudgetError struct {
+	Limit int64 // value of MaxBytes
+}
+
+func (e *BudgetError) Error() string {
+	return "soap: decoded tree exceeds memory budget of " +
+		strconv.FormatInt(e.Limit, 10) + " bytes"
+}
+
+func noFieldError(name string) error {
+	return errors.New("soap: there is no field of name '" + name + "'")
+}
@@ -0,0 +1,177 @@
+package soap
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// fieldFacets returns the function that checks values of field name of type
+// t against facets given by opts or nil if opts give none. Options:
+//
+//	min=N, max=N  minimum and maximum number of items of slices and arrays
+//	              (minOccurs and maxOccurs of repeated elements); max can
+//	              be "unbounded"
+//	length=N,     exact, minimum and maximum length of strings (in
+//	minLength=N,  characters) and []byte (in bytes)
+//	maxLength=N
+//	pattern=RE    regular expression that must match whole strings, as
+//	              xsd:pattern does; it must be the last option, so RE can
+//	              contain commas
+//
+// Facets of pointer fields apply to values pointed to.
+//
+// Facets are checked when values are written and when present elements are
+// loaded.
+func fieldFacets(t reflect.Type, name string, opts tagOptions) func(reflect.Value) error {
+	if t.Kind() == reflect.Ptr {
+		check := fieldFacets(t.Elem(), name, opts)
+		if check == nil {
+			return nil
+		}
+		return func(v reflect.Value) error {
+			if v.IsNil() {
+				return nil
+			}
+			return check(v.Elem())
+		}
+	}
+	var checks []func(reflect.Value) error
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if c := occursCheck(name, opts); c != nil {
+			checks = append(checks, c)
+		}
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			if c := lengthCheck(name, opts, "bytes", reflect.Value.Len); c != nil {
+				checks = append(checks, c)
+			}
+		}
+	case reflect.String:
+		runes := func(v reflect.Value) int {
+			return utf8.RuneCountInString(v.String())
+		}
+		if c := lengthCheck(name, opts, "characters", runes); c != nil {
+			checks = append(checks, c)
+		}
+		if c := patternCheck(name, opts); c != nil {
+			checks = append(checks, c)
+		}
+	}
+	switch len(checks) {
+	case 0:
+		return nil
+	case 1:
+		return checks[0]
+	}
+	return func(v reflect.Value) error {
+		for _, c := range checks {
+			if err := c(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// occursCheck returns the check of min and max options or nil.
+func occursCheck(name string, opts tagOptions) func(reflect.Value) error {
+	min, max := opts.Get("min"), opts.Get("max")
+	if min == "" && max == "" {
+		return nil
+	}
+	lo, err := parseLimit("min", min, 0)
+	if err != nil {
+		return func(reflect.Value) error { return err }
+	}
+	hi, err := parseLimit("max", max, -1)
+	if err != nil {
+		return func(reflect.Value) error { return err }
+	}
+	return func(v reflect.Value) error {
+		n := v.Len()
+		switch {
+		case n < lo:
+			return &FacetError{"min", min, strconv.Itoa(n) + " items", name}
+		case hi >= 0 && n > hi:
+			return &FacetError{"max", max, strconv.Itoa(n) + " items", name}
+		}
+		return nil
+	}
+}
+
+// lengthCheck returns the check of length, minLength and maxLength options or
+// nil. length returns the length of a value in units.
+func lengthCheck(name string, opts tagOptions, units string, length func(reflect.Value) int) func(reflect.Value) error {
+	type limit struct {
+		opt, s string
+		n      int
+	}
+	var limits []limit
+	for _, opt := range [...]string{"length", "minLength", "maxLength"} {
+		s := opts.Get(opt)
+		if s == "" {
+			continue
+		}
+		n, err := parseLimit(opt, s, 0)
+		if err != nil || n < 0 {
+			if err == nil {
+				err = errors.New("soap: bad " + opt + " option: " + s)
+			}
+			return func(reflect.Value) error { return err }
+		}
+		limits = append(limits, limit{opt, s, n})
+	}
+	if limits == nil {
+		return nil
+	}
+	return func(v reflect.Value) error {
+		n := length(v)
+		for _, l := range limits {
+			if l.opt == "length" && n != l.n ||
+				l.opt == "minLength" && n < l.n ||
+				l.opt == "maxLength" && n > l.n {
+				return &FacetError{l.opt, l.s, strconv.Itoa(n) + " " + units, name}
+			}
+		}
+		return nil
+	}
+}
+
+// patternCheck returns the check of the pattern option or nil.
+func patternCheck(name string, opts tagOptions) func(reflect.Value) error {
+	pattern := opts.Rest("pattern")
+	if pattern == "" {
+		return nil
+	}
+	// Patterns of XML Schema are anchored at both ends.
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		err = errors.New("soap: bad pattern option: " + err.Error())
+		return func(reflect.Value) error { return err }
+	}
+	return func(v reflect.Value) error {
+		if s := v.String(); !re.MatchString(s) {
+			return &FacetError{"pattern", pattern, s, name}
+		}
+		return nil
+	}
+}
+
+// parseLimit parses value s of a numeric option. It returns def if s is
+// empty and -1 if s is "unbounded".
+func parseLimit(opt, s string, def int) (int, error) {
+	switch s {
+	case "":
+		return def, nil
+	case "unbounded":
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, errors.New("soap: bad " + opt + " option: " + s)
+	}
+	return n, nil
+}
@@ -0,0 +1,120 @@
+package soap
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// field describes an exported struct field that takes part in encoding and
+// decoding.
+type field struct {
+	index     int
+	name      string // element name
+	typ       reflect.Type
+	opts      tagOptions
+	omitEmpty bool
+	in        bool     // only loaded, never encoded
+	union     []string // member types of the union option
+	choice    string   // group of the choice option
+
+	digits *digitsFacet // totalDigits and fractionDigits options or nil
+
+	// check validates values against facets given by tag options (see
+	// fieldFacets). It is nil if there are none.
+	check func(v reflect.Value) error
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// cachedFields returns the fields of struct type t, computing them only once
+// per type.
+func cachedFields(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fs.([]field)
+}
+
+func typeFields(t reflect.Type) []field {
+	var fs []field
+	n := t.NumField()
+	for i := 0; i < n; i++ {
+		ft := t.Field(i)
+		if ft.PkgPath != "" {
+			continue // unexported field
+		}
+		if ft.Name == "XMLName" && ft.Type == xmlNameType {
+			continue // see structXMLName
+		}
+		name, opts := parseTag(fieldTag(ft.Tag))
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = ft.Name
+			st := ft.Type
+			if st.Kind() == reflect.Ptr {
+				st = st.Elem()
+			}
+			if st.Kind() == reflect.Struct {
+				if local := structXMLName(st).name.Local; local != "" {
+					name = local
+				}
+			}
+		}
+		choice := opts.Get("choice")
+		fs = append(fs, field{
+			index: i,
+			name:  name,
+			typ:   ft.Type,
+			opts:  opts,
+			// Alternatives that aren't chosen are omitted.
+			omitEmpty: opts.Contains("omitempty") || choice != "",
+			in:        opts.Contains("in"),
+			union:     unionMembers(opts),
+			choice:    choice,
+			digits:    newDigitsFacet(ft.Type, name, opts),
+			check:     fieldFacets(ft.Type, name, opts),
+		})
+	}
+	return fs
+}
+
+// checkChoice returns an error if more than one field of a choice group in fs
+// is set or, if needOne is true, if none is. set reports whether the i-th
+// field is set.
+func checkChoice(fs []field, needOne bool, set func(i int) bool) error {
+	for i, f := range fs {
+		if f.choice == "" || firstInGroup(fs, f.choice) != i {
+			continue
+		}
+		chosen := ""
+		for j := i; j < len(fs); j++ {
+			if fs[j].choice != f.choice || !set(j) {
+				continue
+			}
+			if chosen != "" {
+				return errors.New(
+					"soap: choice " + f.choice + " has both " + chosen +
+						" and " + fs[j].name,
+				)
+			}
+			chosen = fs[j].name
+		}
+		if chosen == "" && needOne {
+			return errors.New("soap: no alternative of choice " + f.choice)
+		}
+	}
+	return nil
+}
+
+func firstInGroup(fs []field, choice string) int {
+	for i := range fs {
+		if fs[i].choice == choice {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,93 @@
+package soap
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// appendValue appends text representation of v, which should be a value
+// returned by Element.Value, to b. Lists and maps are formatted as by fmt's
+// %v verb (with map keys sorted), times as in SOAP messages, []byte as
+// base64, QName and partial dates by their String methods and other values
+// by fmt.
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "<nil>"...)
+
+	case string:
+		return append(b, v...)
+
+	case bool:
+		return strconv.AppendBool(b, v)
+
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+
+	case float64:
+		return strconv.AppendFloat(b, v, 'g', -1, 64)
+
+	case time.Time:
+		return v.AppendFormat(b, timeFormatSOAP)
+
+	case []byte:
+		n := len(b)
+		b = append(b, make([]byte, base64.StdEncoding.EncodedLen(len(v)))...)
+		base64.StdEncoding.Encode(b[n:], v)
+		return b
+
+	case QName, GYear, GMonth, GDay, GMonthDay, fmt.Stringer:
+		return append(b, v.(fmt.Stringer).String()...)
+
+	case []interface{}:
+		b = append(b, '[')
+		for i, x := range v {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = appendValue(b, x)
+		}
+		return append(b, ']')
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = append(b, "map["...)
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = append(b, k...)
+			b = append(b, ':')
+			b = appendValue(b, v[k])
+		}
+		return append(b, ']')
+
+	case map[interface{}]interface{}:
+		items := make([]string, 0, len(v))
+		for k, x := range v {
+			item := appendValue(nil, k)
+			item = append(item, ':')
+			items = append(items, string(appendValue(item, x)))
+		}
+		sort.Strings(items)
+		b = append(b, "map["...)
+		for i, item := range items {
+			if i > 0 {
+				b = append(b, ' ')
+			}
+			b = append(b, item...)
+		}
+		return append(b, ']')
+	}
+	return fmt.Append(b, v)
+}
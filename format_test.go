@@ -0,0 +1,30 @@
+package soap
+
+import "testing"
+
+func TestAppendValue(t *testing.T) {
+	type S struct {
+		B []byte
+		Y GYear
+		M GMonthDay
+		Q QName
+	}
+	e := MakeElement("S", S{[]byte{1, 2}, 2024, GMonthDay{12, 31}, QName{"urn:x", "n"}})
+	want := "map[B:AQI= M:--12-31 Q:{urn:x}n Y:2024]"
+	if got := e.AsStr(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	cases := []struct {
+		v    interface{}
+		want string
+	}{
+		{[]interface{}{int32(1), "a", nil}, "[1 a <nil>]"},
+		{map[interface{}]interface{}{int64(2): 1.5, int64(1): true}, "map[1:true 2:1.5]"},
+		{[]byte("hi"), "aGk="},
+	}
+	for _, c := range cases {
+		if got := string(appendValue(nil, c.v)); got != c.want {
+			t.Errorf("got %s, want %s", got, c.want)
+		}
+	}
+}
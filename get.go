@@ -0,0 +1,137 @@
+package soap
+
+import (
+	"strconv"
+	"time"
+)
+
+// Scalar is the set of types that can be obtained by Get and As.
+type Scalar interface {
+	string | bool |
+		int | int64 | int32 | int16 | int8 |
+		uint | uint64 | uint32 | uint16 | uint8 |
+		float64 | float32 |
+		time.Time
+}
+
+// Get returns the value of the child of e with given key (see Element.Get)
+// as T, e.g. Get[int64](e, "Count"). Unlike GetValue it doesn't box the value
+// in interface{}. The type of the child must match T, as required by
+// Element.Int64, Element.Str and other typed accessors.
+func Get[T Scalar](e *Element, key interface{}) (T, error) {
+	var v T
+	c, err := e.Get(key)
+	if err != nil {
+		return v, err
+	}
+	if c == nil {
+		return v, noFieldError(string(appendValue(nil, mapKey(key))))
+	}
+	if err = c.scan(&v); err != nil {
+		return v, inPath(err, e.XMLName.Local)
+	}
+	return v, nil
+}
+
+// As works like Get but converts the value of the child as the As* accessors
+// do, e.g. As[int](e, "Count") accepts any integer SOAP type (or an untyped
+// element) with a value that fits in int.
+func As[T Scalar](e *Element, key interface{}) (T, error) {
+	var v T
+	c, err := e.Get(key)
+	if err != nil {
+		return v, err
+	}
+	if c == nil {
+		return v, noFieldError(string(appendValue(nil, mapKey(key))))
+	}
+	if err = c.scanAs(&v); err != nil {
+		return v, inPath(err, e.XMLName.Local)
+	}
+	return v, nil
+}
+
+// scan stores the value of e in the variable pointed by p. Types must match.
+func (e *Element) scan(p interface{}) (err error) {
+	switch p := p.(type) {
+	case *string:
+		*p, err = e.Str()
+	case *bool:
+		*p, err = e.Bool()
+	case *int:
+		var i int64
+		i, err = e.Int(64)
+		*p = int(i)
+	case *uint:
+		var u uint64
+		u, err = e.Uint(64)
+		*p = uint(u)
+	case *int64:
+		*p, err = e.Int64()
+	case *int32:
+		*p, err = e.Int32()
+	case *int16:
+		*p, err = e.Int16()
+	case *int8:
+		*p, err = e.Int8()
+	case *uint64:
+		*p, err = e.Uint64()
+	case *uint32:
+		*p, err = e.Uint32()
+	case *uint16:
+		*p, err = e.Uint16()
+	case *uint8:
+		*p, err = e.Uint8()
+	case *float64:
+		*p, err = e.Float64()
+	case *float32:
+		*p, err = e.Float32()
+	case *time.Time:
+		*p, err = e.Time()
+	}
+	return
+}
+
+// scanAs works like scan but converts the value as the As* accessors do.
+func (e *Element) scanAs(p interface{}) (err error) {
+	switch p := p.(type) {
+	case *string:
+		*p = e.AsStr()
+	case *bool:
+		*p, err = e.AsBool()
+	case *int:
+		var i int64
+		i, err = e.AsInt(strconv.IntSize)
+		*p = int(i)
+	case *uint:
+		var u uint64
+		u, err = e.AsUint(strconv.IntSize)
+		*p = uint(u)
+	case *int64:
+		*p, err = e.AsInt64()
+	case *int32:
+		*p, err = e.AsInt32()
+	case *int16:
+		*p, err = e.AsInt16()
+	case *int8:
+		*p, err = e.AsInt8()
+	case *uint64:
+		*p, err = e.AsUint64()
+	case *uint32:
+		*p, err = e.AsUint32()
+	case *uint16:
+		*p, err = e.AsUint16()
+	case *uint8:
+		*p, err = e.AsUint8()
+	case *float64:
+		*p, err = e.AsFloat64()
+	case *float32:
+		*p, err = e.AsFloat32()
+	case *time.Time:
+		*p, err = e.AsTime(DefaultDecoder.location())
+		if DefaultDecoder.UTC {
+			*p = p.UTC()
+		}
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package soap
+
+import "testing"
+
+type getPayload struct {
+	Name  string
+	Count int64
+	Small int8
+	Ratio float64
+}
+
+func getElement() *Element {
+	return MakeElement("R", getPayload{"x", 42, -5, 0.5})
+}
+
+func TestGetInt8(t *testing.T) {
+	e := getElement()
+	v, err := Get[int8](e, "Small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != -5 {
+		t.Fatalf("got %d, want -5", v)
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	e := getElement()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Get[int64](e, "Count"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAs(b *testing.B) {
+	e := getElement()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := As[float64](e, "Count"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetValue is the boxing counterpart of BenchmarkGet.
+func BenchmarkGetValue(b *testing.B) {
+	e := getElement()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := e.GetValue("Count"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
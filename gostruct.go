@@ -0,0 +1,182 @@
+package soap
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// StructSource returns Go source (package pkg) with struct definitions that
+// LoadStruct can load e into. Field types are taken from xsi:type attributes.
+// For untyped elements they are guessed from the sample values, so the result
+// is a starting point for an integration with an undocumented service rather
+// than a final definition.
+func StructSource(pkg string, e *Element) ([]byte, error) {
+	e.Expand()
+	g := &structGen{bodies: make(map[string]string)}
+	root := g.goType(e)
+	if len(g.order) == 0 {
+		return nil, fmt.Errorf(
+			"soap: element '%s' of type %s isn't a struct",
+			e.XMLName.Local, root,
+		)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if g.useTime {
+		buf.WriteString("import \"time\"\n\n")
+	}
+	for _, name := range g.order {
+		fmt.Fprintf(&buf, "type %s struct {\n%s}\n\n", name, g.bodies[name])
+	}
+	return format.Source(buf.Bytes())
+}
+
+type structGen struct {
+	bodies  map[string]string
+	order   []string
+	useTime bool
+}
+
+// goIdent converts an XML name to an exported Go identifier.
+func goIdent(name string) string {
+	name = skipNS(name)
+	var b strings.Builder
+	up := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			up = true
+			continue
+		}
+		if up {
+			r = unicode.ToUpper(r)
+			up = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// goType returns the name of Go type for e, defining structs when necessary.
+func (g *structGen) goType(e *Element) string {
+	switch skipNS(e.Type) {
+	case "string":
+		return "string"
+	case "boolean":
+		return "bool"
+	case "long":
+		return "int64"
+	case "int":
+		return "int32"
+	case "short":
+		return "int16"
+	case "byte":
+		return "int8"
+	case "unsignedLong":
+		return "uint64"
+	case "unsignedInt":
+		return "uint32"
+	case "unsignedShort":
+		return "uint16"
+	case "unsignedByte":
+		return "uint8"
+	case "float":
+		return "float32"
+	case "double":
+		return "float64"
+	case "dateTime":
+		g.useTime = true
+		return "time.Time"
+	case "Map":
+		return "map[interface{}]interface{}"
+	case "Array":
+		if len(e.Children) == 0 {
+			return "[]interface{}"
+		}
+		return "[]" + g.goType(e.Children[0])
+	case "Struct":
+		return g.structType(e)
+	case "":
+		if len(e.Children) != 0 {
+			return g.structType(e)
+		}
+		if e.Nil {
+			return "*string"
+		}
+		return g.guessType(strings.TrimSpace(e.Text))
+	}
+	return "string"
+}
+
+func (g *structGen) guessType(s string) string {
+	if s == "true" || s == "false" {
+		return "bool"
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return "int64"
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return "float64"
+	}
+	if _, err := parseDateTime(s, time.UTC); err == nil {
+		g.useTime = true
+		return "time.Time"
+	}
+	return "string"
+}
+
+func (g *structGen) structType(e *Element) string {
+	var (
+		body  strings.Builder
+		names []string
+		count = make(map[string]int)
+		first = make(map[string]*Element)
+	)
+	for _, c := range e.Children {
+		name := c.XMLName.Local
+		if count[name] == 0 {
+			names = append(names, name)
+			first[name] = c
+		}
+		count[name]++
+	}
+	for _, name := range names {
+		typ := g.goType(first[name])
+		if count[name] > 1 {
+			typ = "[]" + typ
+		}
+		field := goIdent(name)
+		if field == name {
+			fmt.Fprintf(&body, "\t%s %s\n", field, typ)
+		} else {
+			fmt.Fprintf(&body, "\t%s %s `soap:\"%s\"`\n", field, typ, name)
+		}
+	}
+	return g.define(goIdent(e.XMLName.Local), body.String())
+}
+
+// define registers struct type with given body under base name or, if the
+// name is taken by a different struct, under a numbered variant of it.
+func (g *structGen) define(base, body string) string {
+	name := base
+	for i := 2; ; i++ {
+		b, ok := g.bodies[name]
+		if !ok {
+			g.bodies[name] = body
+			g.order = append(g.order, name)
+			return name
+		}
+		if b == body {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
@@ -0,0 +1,72 @@
+package soap
+
+// indexThreshold is the number of children from which Get uses an index
+// instead of scanning children.
+const indexThreshold = 8
+
+// childIndex maps names of children to their positions in Children.
+type childIndex struct {
+	n   int // length of Children the index was built for
+	pos map[string]int
+}
+
+func (e *Element) buildIndex() *childIndex {
+	idx := &childIndex{n: len(e.Children), pos: make(map[string]int)}
+	for i, c := range e.Children {
+		if c == nil {
+			continue
+		}
+		if _, ok := idx.pos[c.XMLName.Local]; !ok {
+			idx.pos[c.XMLName.Local] = i
+		}
+	}
+	e.index.Store(idx)
+	return idx
+}
+
+// child returns the first child of e of given name or nil. Elements with many
+// children are searched using an index built on first use and rebuilt when
+// the number of children changes.
+func (e *Element) child(name string) *Element {
+	e.expand()
+	if len(e.Children) < indexThreshold {
+		for _, c := range e.Children {
+			if c != nil && c.XMLName.Local == name {
+				return c
+			}
+		}
+		return nil
+	}
+	idx := e.index.Load()
+	if idx == nil || idx.n != len(e.Children) {
+		idx = e.buildIndex()
+	}
+	i, ok := idx.pos[name]
+	if !ok {
+		return nil
+	}
+	if c := e.Children[i]; c != nil && c.XMLName.Local == name {
+		return c
+	}
+	// Children were replaced in place.
+	if i, ok = e.buildIndex().pos[name]; ok {
+		return e.Children[i]
+	}
+	return nil
+}
+
+// AddChild appends c to children of e.
+func (e *Element) AddChild(c *Element) {
+	e.expand()
+	e.Children = append(e.Children, c)
+	e.index.Store(nil)
+}
+
+// SetChildren replaces children of e with cs. Use it (or call
+// e.SetChildren(e.Children)) after modifying e.Children in place, so Get
+// doesn't use a stale index.
+func (e *Element) SetChildren(cs []*Element) {
+	e.Children = cs
+	e.lazy = nil
+	e.index.Store(nil)
+}
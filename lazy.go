@@ -0,0 +1,203 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sync"
+)
+
+// lazySpan holds the unparsed source of an element whose children are
+// materialized on first access.
+type lazySpan struct {
+	mu   sync.Mutex
+	done bool
+	src  []byte     // the element including its start and end tags
+	ns   []xml.Attr // namespace declarations in scope of the element
+}
+
+// decodeLazy reads the whole document from r and returns its root element
+// with children materialized on demand.
+func (dec *Decoder) decodeLazy(r io.Reader) (*Element, error) {
+	if dec.MaxBytes > 0 {
+		r = io.LimitReader(r, dec.MaxBytes+1)
+	}
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if dec.MaxBytes > 0 && int64(len(src)) > dec.MaxBytes {
+		return nil, &BudgetError{dec.MaxBytes}
+	}
+	d := xml.NewDecoder(bytes.NewReader(src))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			e := newElement()
+			names := make(interner)
+			e.setStart(start, names)
+			e.scope = inScope(nil, start)
+			err = e.parseShallow(d, src, e.scope, names)
+			if err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	}
+}
+
+// parseShallow reads the content of e from d, which is positioned just after
+// the start tag of e. Text of direct children is set, but their own children
+// are only recorded as spans of src, parsed when first needed. ns contains
+// namespace declarations in scope of children.
+func (e *Element) parseShallow(d *xml.Decoder, src []byte, ns []xml.Attr, names interner) error {
+	// Char data of e and of the current child, converted to Text once.
+	var text, childText []byte
+	for {
+		off := d.InputOffset()
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			c := newElement()
+			c.setStart(t, names)
+			c.scope = inScope(ns, t)
+			childText = childText[:0]
+			nested := false
+			for done := false; !done; {
+				tok, err = d.Token()
+				if err != nil {
+					if err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+					return err
+				}
+				switch tt := tok.(type) {
+				case xml.StartElement:
+					nested = true
+					if err = d.Skip(); err != nil {
+						return err
+					}
+				case xml.EndElement:
+					done = true
+				case xml.CharData:
+					childText = append(childText, tt...)
+				}
+			}
+			if len(childText) != 0 {
+				c.Text = string(childText)
+			}
+			if nested {
+				c.lazy = &lazySpan{src: src[off:d.InputOffset()], ns: ns}
+			}
+			e.Children = append(e.Children, c)
+
+		case xml.EndElement:
+			if len(text) != 0 {
+				e.Text = string(text)
+			}
+			return nil
+
+		case xml.CharData:
+			text = append(text, t...)
+		}
+	}
+}
+
+// inScope returns ns extended by namespace declarations of start. ns isn't
+// modified.
+func inScope(ns []xml.Attr, start xml.StartElement) []xml.Attr {
+	var out []xml.Attr
+	for _, a := range start.Attr {
+		if a.Name.Space != "xmlns" && (a.Name.Space != "" || a.Name.Local != "xmlns") {
+			continue
+		}
+		if out == nil {
+			out = append(make([]xml.Attr, 0, len(ns)+1), ns...)
+		}
+		replaced := false
+		for i := range out {
+			if out[i].Name == a.Name {
+				out[i].Value = a.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			out = append(out, a)
+		}
+	}
+	if out == nil {
+		return ns
+	}
+	return out
+}
+
+// expand materializes children of e if they weren't decoded yet.
+func (e *Element) expand() {
+	l := e.lazy
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.done {
+		return
+	}
+	l.done = true
+
+	// Wrap the source in an element that declares namespaces in scope.
+	b := []byte("<_")
+	for _, a := range l.ns {
+		b = append(b, " xmlns"...)
+		if a.Name.Space == "xmlns" {
+			b = append(b, ':')
+			b = append(b, a.Name.Local...)
+		}
+		b = append(b, `="`...)
+		b = appendEscaped(b, a.Value, true)
+		b = append(b, '"')
+	}
+	b = append(b, '>')
+	b = append(b, l.src...)
+	b = append(b, "</_>"...)
+
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.Token() // wrapper
+	tok, _ := d.Token()
+	start, _ := tok.(xml.StartElement)
+	text := e.Text
+	// The source was checked when it was skipped, so it is well formed.
+	e.parseShallow(d, b, inScope(l.ns, start), make(interner))
+	e.Text = text
+}
+
+// hasChildren reports whether e has child elements, without materializing
+// them.
+func (e *Element) hasChildren() bool {
+	return len(e.Children) != 0 || e.lazy != nil
+}
+
+// Expand materializes the whole tree of e. Trees returned by a Decoder with
+// Lazy set decode children only when they are accessed by Get, Value,
+// LoadStruct or Encoder. Call Expand before reading Children directly.
+func (e *Element) Expand() {
+	stack := []*Element{e}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		e.expand()
+		for _, c := range e.Children {
+			if c != nil && c.hasChildren() {
+				stack = append(stack, c)
+			}
+		}
+	}
+}
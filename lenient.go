@@ -0,0 +1,63 @@
+package soap
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// normalizeNumber converts a number written in a locale format, e.g.
+// "1 234,56" or "1.234.567,8", into the form accepted by strconv.ParseFloat.
+// Spaces (including non-breaking ones) and apostrophes are dropped. If both
+// '.' and ',' occur, the last one is the decimal separator and the other
+// separates thousands. A single ',' is the decimal separator and repeated
+// '.' or ',' separate thousands.
+func normalizeNumber(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, s)
+	dot, comma := strings.LastIndexByte(s, '.'), strings.LastIndexByte(s, ',')
+	var group, dec byte
+	switch {
+	case dot != -1 && comma != -1:
+		if dot > comma {
+			group, dec = ',', '.'
+		} else {
+			group, dec = '.', ','
+		}
+	case comma != -1:
+		if strings.Count(s, ",") > 1 {
+			group = ','
+		} else {
+			dec = ','
+		}
+	case dot != -1:
+		if strings.Count(s, ".") > 1 {
+			group = '.'
+		}
+	}
+	if group != 0 {
+		s = strings.ReplaceAll(s, string(group), "")
+	}
+	if dec == ',' {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return s
+}
+
+// asLenientFloat works like AsFloat but accepts numbers in locale formats
+// (see normalizeNumber).
+func (e *Element) asLenientFloat(bits int) (float64, error) {
+	if e.hasChildren() || e.Nil {
+		return e.AsFloat(bits)
+	}
+	v, err := strconv.ParseFloat(normalizeNumber(e.Text), bits)
+	if err != nil {
+		t, _ := goFloatTypeName(bits)
+		return 0, e.badValue(t, err)
+	}
+	return v, nil
+}
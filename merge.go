@@ -0,0 +1,97 @@
+package soap
+
+// Merge updates the tree of e with children of other, matched by name and,
+// for repeated children, by their position among children of that name:
+// children with xsi:nil set delete the matching children of e, struct
+// children are merged recursively and other children (scalars, arrays and
+// maps) replace the matching children of e or are appended if e has none
+// (so extra repeated children of other are appended).
+// Elements of other are copied, so other can be released or modified later.
+func (e *Element) Merge(other *Element) {
+	type pair struct{ dst, src *Element }
+	stack := []pair{{e, other}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		p.dst.expand()
+		p.src.expand()
+		children := p.dst.Children
+		seen := make(map[string]int)
+		for _, s := range p.src.Children {
+			if s == nil {
+				continue
+			}
+			name := s.XMLName.Local
+			if s.Nil {
+				children = removeChildren(children, name)
+				seen[name] = 0
+				continue
+			}
+			i := nthChild(children, name, seen[name])
+			seen[name]++
+			switch {
+			case i == len(children):
+				children = append(children, s.Clone())
+			case isStruct(children[i]) && isStruct(s):
+				stack = append(stack, pair{children[i], s})
+			default:
+				children[i] = s.Clone()
+			}
+		}
+		p.dst.SetChildren(children)
+	}
+}
+
+// ApplyPatch merges patch, made into an element as Marshal does, into e. Nil
+// pointer fields of patch delete the matching children and omitted fields
+// (e.g. nil pointers with the omitempty option) leave them unchanged.
+func (e *Element) ApplyPatch(patch interface{}) error {
+	p, err := Marshal(e.XMLName.Local, patch)
+	if err != nil {
+		return err
+	}
+	e.Merge(p)
+	p.Release()
+	return nil
+}
+
+// isStruct reports whether e has children that are merged by name.
+func isStruct(e *Element) bool {
+	if !e.hasChildren() {
+		return false
+	}
+	switch skipNS(e.Type) {
+	case "Array", "Map":
+		return false
+	}
+	return true
+}
+
+// nthChild returns the index in cs of the n-th (from 0) element of given
+// name or len(cs) if there are not so many.
+func nthChild(cs []*Element, name string, n int) int {
+	for i, c := range cs {
+		if c != nil && c.XMLName.Local == name {
+			if n == 0 {
+				return i
+			}
+			n--
+		}
+	}
+	return len(cs)
+}
+
+// removeChildren removes elements of given name from cs in place.
+func removeChildren(cs []*Element, name string) []*Element {
+	n := 0
+	for _, c := range cs {
+		if c == nil || c.XMLName.Local != name {
+			cs[n] = c
+			n++
+		}
+	}
+	for i := n; i < len(cs); i++ {
+		cs[i] = nil
+	}
+	return cs[:n]
+}
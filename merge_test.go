@@ -0,0 +1,40 @@
+package soap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeRepeated(t *testing.T) {
+	dec := func(s string) *Element {
+		e, err := new(Decoder).Decode(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	cases := []struct{ dst, src, want string }{
+		{
+			`<R><Line>a</Line><Line>b</Line><Sum>2</Sum></R>`,
+			`<R><Line>x</Line><Line>y</Line><Line>z</Line></R>`,
+			`<R><Line>x</Line><Line>y</Line><Sum>2</Sum><Line>z</Line></R>`,
+		},
+		{
+			`<R><Line>a</Line><Line>b</Line><Line>c</Line></R>`,
+			`<R><Line>x</Line></R>`,
+			`<R><Line>x</Line><Line>b</Line><Line>c</Line></R>`,
+		},
+		{
+			`<R><P><A>1</A></P><P><A>2</A><B>3</B></P></R>`,
+			`<R><P><B>5</B></P><P><A>4</A></P></R>`,
+			`<R><P><A>1</A><B>5</B></P><P><A>4</A><B>3</B></P></R>`,
+		},
+	}
+	for _, c := range cases {
+		e := dec(c.dst)
+		e.Merge(dec(c.src))
+		if b := new(Encoder).AppendElement(nil, e); string(b) != c.want {
+			t.Errorf("got  %s\nwant %s", b, c.want)
+		}
+	}
+}
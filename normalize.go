@@ -0,0 +1,138 @@
+package soap
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sort sorts children of all elements of the tree of e by name and items of
+// maps by text of their keys. Sorting is stable and items of arrays keep
+// their order. Elements of mixed content (see Decoder.Mixed) are reordered
+// the same way, while text between them stays in place. Together with Normalize it makes a canonical form of trees
+// that differ only in order of struct fields, useful for cache keys and
+// golden files.
+func (e *Element) Sort() {
+	stack := []*Element{e}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		e.expand()
+		cs := e.Children
+		switch skipNS(e.Type) {
+		case "Array":
+		case "Map":
+			sort.SliceStable(cs, func(i, j int) bool {
+				return itemKey(cs[i]) < itemKey(cs[j])
+			})
+		default:
+			sort.SliceStable(cs, func(i, j int) bool {
+				return childName(cs[i]) < childName(cs[j])
+			})
+		}
+		e.SetChildren(cs)
+		if len(e.Content) != 0 {
+			k := 0
+			for i := range e.Content {
+				if e.Content[i].Elem == nil {
+					continue
+				}
+				for k < len(cs) && cs[k] == nil {
+					k++
+				}
+				if k < len(cs) {
+					e.Content[i].Elem = cs[k]
+					k++
+				}
+			}
+		}
+		for _, c := range cs {
+			if c != nil && c.hasChildren() {
+				stack = append(stack, c)
+			}
+		}
+	}
+}
+
+func childName(e *Element) string {
+	if e == nil {
+		return ""
+	}
+	return e.XMLName.Local
+}
+
+// itemKey returns text of the key of map item e.
+func itemKey(e *Element) string {
+	if e == nil {
+		return ""
+	}
+	if k := e.child("key"); k != nil {
+		return k.Text
+	}
+	return ""
+}
+
+// Normalize trims white space surrounding text of all elements of the tree
+// of e and removes empty elements (without text, children, attributes,
+// xsi:type and xsi:nil) that aren't items of arrays or maps or keys and
+// values of map items, as omitted optional elements are equivalent to them.
+// Typed empty elements (e.g. empty strings) are kept, as they differ from
+// omitted ones. Mixed content is dropped. e itself is never removed.
+// Elements in xml:space="preserve" scopes are kept as they are.
+func (e *Element) Normalize() {
+	// Children are processed before their parents, so parents emptied by
+	// removing their children are removed too.
+	type frame struct {
+		e        *Element
+		preserve bool
+		mapItem  bool // key and value are kept even if empty
+		done     bool
+	}
+	stack := []frame{{e: e, preserve: e.preserveSpace(false)}}
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		if f.preserve {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if !f.done {
+			f.done = true
+			f.e.expand()
+			isMap := skipNS(f.e.Type) == "Map"
+			for _, c := range f.e.Children {
+				if c != nil {
+					stack = append(stack, frame{
+						e: c, preserve: c.preserveSpace(false), mapItem: isMap,
+					})
+				}
+			}
+			continue
+		}
+		e, mapItem := f.e, f.mapItem
+		stack = stack[:len(stack)-1]
+		e.Text = strings.TrimSpace(e.Text)
+		e.Content = nil
+		switch skipNS(e.Type) {
+		case "Array", "Map":
+			continue
+		}
+		if mapItem {
+			continue
+		}
+		cs := e.Children[:0]
+		for _, c := range e.Children {
+			if c != nil && !c.empty() {
+				cs = append(cs, c)
+			}
+		}
+		for i := len(cs); i < len(e.Children); i++ {
+			e.Children[i] = nil
+		}
+		e.SetChildren(cs)
+	}
+}
+
+// empty reports whether e has no value.
+func (e *Element) empty() bool {
+	return e.Text == "" && e.Type == "" && !e.hasChildren() && len(e.Attrs) == 0 &&
+		!e.Nil && e.stream == nil
+}
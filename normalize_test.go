@@ -0,0 +1,67 @@
+package soap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizePreserve(t *testing.T) {
+	doc := `<R><A> a </A><E/><T xml:space="preserve"> t <E/><B> b </B></T>` +
+		`<U xml:space="default"> u </U></R>`
+	e, err := NewDecoder(WithMixed(true)).Decode(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Normalize()
+	want := `<R><A>a</A><T xml:space="preserve"> t <E/><B> b </B></T><U xml:space="default">u</U></R>`
+	if b := new(Encoder).AppendElement(nil, e); string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+}
+
+func TestNormalizeKeepsTyped(t *testing.T) {
+	e := MakeElement("S", struct {
+		Name string
+		M    map[string]string
+	}{M: map[string]string{"": ""}})
+	e.Normalize()
+	if len(e.Children) != 2 || e.Children[0].XMLName.Local != "Name" {
+		t.Fatalf("typed empty string removed: %d children", len(e.Children))
+	}
+	m := e.Children[1]
+	if len(m.Children) != 1 {
+		t.Fatalf("map has %d items", len(m.Children))
+	}
+	if _, _, err := m.Children[0].MapItem(); err != nil {
+		t.Error(err)
+	}
+
+	// Untyped empty elements are removed, except in map items.
+	e, err := new(Decoder).Decode(strings.NewReader(
+		`<R><E/><M xsi:type="ns2:Map" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+			`<item><key/><value/></item></M></R>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Normalize()
+	want := `<R><M xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="ns2:Map">` +
+		`<item><key/><value/></item></M></R>`
+	if b := new(Encoder).AppendElement(nil, e); string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+}
+
+func TestSortMixed(t *testing.T) {
+	e, err := NewDecoder(WithMixed(true)).Decode(strings.NewReader(
+		`<R>1<B>b</B>2<A>a</A>3</R>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Sort()
+	want := `<R>1<A>a</A>2<B>b</B>3</R>`
+	if b := new(Encoder).AppendElement(nil, e); string(b) != want {
+		t.Errorf("got  %s\nwant %s", b, want)
+	}
+}
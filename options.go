@@ -0,0 +1,86 @@
+package soap
+
+import "time"
+
+// An Option configures an Encoder (see NewEncoder) or a Decoder (see
+// NewDecoder). Options that don't apply to the configured value are ignored,
+// so one set of options can be shared by both.
+type Option struct {
+	enc func(*Encoder)
+	dec func(*Decoder)
+}
+
+// NewDecoder returns a Decoder configured by opts.
+func NewDecoder(opts ...Option) *Decoder {
+	dec := new(Decoder)
+	for _, o := range opts {
+		if o.dec != nil {
+			o.dec(dec)
+		}
+	}
+	return dec
+}
+
+// WithProfile sets Encoder.Profile.
+func WithProfile(p *Profile) Option {
+	return Option{enc: func(enc *Encoder) { enc.Profile = p }}
+}
+
+// WithLimits sets Decoder.MaxDepth and Decoder.MaxBytes.
+func WithLimits(maxDepth int, maxBytes int64) Option {
+	return Option{dec: func(dec *Decoder) {
+		dec.MaxDepth = maxDepth
+		dec.MaxBytes = maxBytes
+	}}
+}
+
+// WithWorkers sets Decoder.Workers.
+func WithWorkers(n int) Option {
+	return Option{dec: func(dec *Decoder) { dec.Workers = n }}
+}
+
+// WithLocation sets Decoder.Location.
+func WithLocation(loc *time.Location) Option {
+	return Option{dec: func(dec *Decoder) { dec.Location = loc }}
+}
+
+// WithUTC sets Profile.UTC of the Encoder (to a modified copy of its profile)
+// and Decoder.UTC.
+func WithUTC(utc bool) Option {
+	return Option{
+		enc: func(enc *Encoder) {
+			p := *enc.profile()
+			p.UTC = utc
+			enc.Profile = &p
+		},
+		dec: func(dec *Decoder) { dec.UTC = utc },
+	}
+}
+
+// WithLazy sets Decoder.Lazy.
+func WithLazy(lazy bool) Option {
+	return Option{dec: func(dec *Decoder) { dec.Lazy = lazy }}
+}
+
+// WithMixed sets Decoder.Mixed.
+func WithMixed(mixed bool) Option {
+	return Option{dec: func(dec *Decoder) { dec.Mixed = mixed }}
+}
+
+// WithLenientNumbers sets Decoder.LenientNumbers.
+func WithLenientNumbers(lenient bool) Option {
+	return Option{dec: func(dec *Decoder) { dec.LenientNumbers = lenient }}
+}
+
+// WithComments sets Decoder.Comments.
+func WithComments(comments bool) Option {
+	return Option{dec: func(dec *Decoder) { dec.Comments = comments }}
+}
+
+// WithSnapshot sets Encoder.Snapshot and Encoder.Mask.
+func WithSnapshot(mask ...string) Option {
+	return Option{enc: func(enc *Encoder) {
+		enc.Snapshot = true
+		enc.Mask = mask
+	}}
+}
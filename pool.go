@@ -0,0 +1,43 @@
+package soap
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var elementPool = sync.Pool{
+	New: func() interface{} {
+		atomic.AddInt64(&stats.Allocated, 1)
+		return new(Element)
+	},
+}
+
+// newElement returns an empty Element, reusing a released one if possible.
+func newElement() *Element {
+	return elementPool.Get().(*Element)
+}
+
+// Release returns e and all its descendants to the pool of elements reused by
+// MakeElement. Releasing trees that are no longer needed (e.g. decoded
+// responses after loading them into structs) reduces GC pressure in busy
+// programs. Neither e nor any element of its tree may be used after Release,
+// so don't release trees that share subtrees with trees still in use.
+func (e *Element) Release() {
+	var n int64
+	stack := []*Element{e}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		children := e.Children
+		for i, c := range children {
+			if c != nil {
+				stack = append(stack, c)
+			}
+			children[i] = nil
+		}
+		*e = Element{Children: children[:0]}
+		elementPool.Put(e)
+		n++
+	}
+	atomic.AddInt64(&stats.Released, n)
+}
@@ -0,0 +1,141 @@
+package soap
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// NamespaceApacheSOAP is the namespace of the Map type used by Apache SOAP,
+// Axis 1 and PHP. The prefix of MapPrefix should be bound to it in the
+// envelope.
+const NamespaceApacheSOAP = "http://xml.apache.org/xml-soap"
+
+// A Profile bundles the encoding conventions of a particular SOAP stack, so
+// elements made by MakeElement are accepted by it. Prefixes used in xsi:type
+// values aren't declared by this package: they must match namespace
+// declarations in the envelope. Empty fields mean the defaults given below.
+type Profile struct {
+	// OmitTypes disables xsi:type attributes (document/literal services).
+	OmitTypes bool
+
+	// XSDPrefix is the prefix of XML Schema types (default "xsd").
+	XSDPrefix string
+
+	// EncPrefix is the prefix of SOAP encoding types, e.g. Struct (default
+	// "SOAP-ENC").
+	EncPrefix string
+
+	// MapPrefix is the prefix of Apache SOAP Map type (default "ns2"), see
+	// NamespaceApacheSOAP.
+	MapPrefix string
+
+	// True and False are literals used for bool values (default "true" and
+	// "false").
+	True, False string
+
+	// MaxDepth limits nesting of made trees (DefaultMaxDepth if 0).
+	MaxDepth int
+
+	// UTC makes dateTime values converted to UTC and written with Z
+	// instead of a numeric offset.
+	UTC bool
+}
+
+var (
+	// DefaultProfile is used by MakeElement. Its conventions are those of
+	// PHP SoapServer/SoapClient and gSOAP rpc/encoded services, so they
+	// need no profile of their own.
+	DefaultProfile = &Profile{}
+
+	// ProfileAxis1 describes Apache Axis 1.x rpc/encoded services.
+	ProfileAxis1 = &Profile{EncPrefix: "soapenc", MapPrefix: "apachesoap"}
+
+	// ProfileDotNet describes .NET (ASMX, WCF basicHttpBinding)
+	// document/literal services, which don't expect xsi:type attributes.
+	ProfileDotNet = &Profile{OmitTypes: true}
+)
+
+func (p *Profile) maxDepth() int {
+	if p.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return p.MaxDepth
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func (p *Profile) xsdType(local string) string {
+	if p.OmitTypes {
+		return ""
+	}
+	return orDefault(p.XSDPrefix, "xsd") + ":" + local
+}
+
+func (p *Profile) encType(local string) string {
+	if p.OmitTypes {
+		return ""
+	}
+	return orDefault(p.EncPrefix, "SOAP-ENC") + ":" + local
+}
+
+func (p *Profile) mapType() string {
+	if p.OmitTypes {
+		return ""
+	}
+	return orDefault(p.MapPrefix, "ns2") + ":Map"
+}
+
+// setArrayType sets the SOAP-ENC:arrayType attribute of array e of n items
+// of Go type t (nil for mixed items), e.g. xsd:string[3]. The prefix of the
+// attribute is declared in e, as encoders of arrays outside of envelopes
+// don't know it.
+func (p *Profile) setArrayType(e *Element, t reflect.Type, n int) {
+	if p.OmitTypes {
+		return
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	typ := "anyType"
+	switch {
+	case t == nil:
+	case t == qnameType:
+		typ = "QName"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		typ = "base64Binary"
+	default:
+		if x := xsdScalarType(t); x != "" {
+			typ = x
+		}
+	}
+	prefix := orDefault(p.EncPrefix, "SOAP-ENC")
+	e.NS = append(e.NS, xml.Attr{
+		Name:  xml.Name{Space: "xmlns", Local: prefix},
+		Value: NamespaceSOAPEnc,
+	})
+	e.Attrs = append(e.Attrs, xml.Attr{
+		Name:  xml.Name{Space: NamespaceSOAPEnc, Local: "arrayType"},
+		Value: p.xsdType(typ) + "[" + strconv.Itoa(n) + "]",
+	})
+}
+
+func (p *Profile) formatTime(t time.Time) string {
+	if p.UTC {
+		return t.UTC().Format(timeFormatSOAPUTC)
+	}
+	return t.Format(timeFormatSOAP)
+}
+
+func (p *Profile) boolLiteral(b bool) string {
+	if b {
+		return orDefault(p.True, "true")
+	}
+	return orDefault(p.False, "false")
+}
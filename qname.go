@@ -0,0 +1,96 @@
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// QName is a value of xsd:QName type: a name qualified by a namespace. It is
+// written as prefix:local with the prefix declared in the element (so the
+// prefix used in the document doesn't matter) and decoded by resolving the
+// prefix in scope of the element.
+type QName struct {
+	Space, Local string
+}
+
+var qnameType = reflect.TypeOf(QName{})
+
+// qnamePrefix is the prefix declared for namespaces of encoded QName values.
+const qnamePrefix = "qn"
+
+func (q QName) String() string {
+	if q.Space == "" {
+		return q.Local
+	}
+	return "{" + q.Space + "}" + q.Local
+}
+
+// setQName sets type and text of e to q, declaring the prefix of q.Space.
+func (p *Profile) setQName(e *Element, q QName) {
+	e.Type = p.xsdType("QName")
+	if q.Space == "" {
+		e.Text = q.Local
+		return
+	}
+	e.NS = append(e.NS, xml.Attr{
+		Name:  xml.Name{Space: "xmlns", Local: qnamePrefix},
+		Value: q.Space,
+	})
+	e.Text = qnamePrefix + ":" + q.Local
+}
+
+// QName returns the value of e of xsd:QName type.
+func (e *Element) QName() (QName, error) {
+	if skipNS(e.Type) != "QName" {
+		return QName{}, e.typeError("QName")
+	}
+	return e.AsQName()
+}
+
+// AsQName returns the text of e as QName regardless of the type of e.
+// Unprefixed names are in the default namespace in scope of e.
+func (e *Element) AsQName() (QName, error) {
+	if e.hasChildren() {
+		return QName{}, e.badValue("QName", nil)
+	}
+	if e.Nil {
+		return QName{}, nil
+	}
+	s := strings.TrimSpace(e.Text)
+	prefix, local := "", s
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		prefix, local = s[:i], s[i+1:]
+	}
+	if local == "" || strings.IndexByte(local, ':') != -1 {
+		return QName{}, e.badValue("QName", nil)
+	}
+	space, ok := e.lookupPrefix(prefix)
+	if !ok {
+		return QName{}, e.badValue(
+			"QName", errors.New("soap: undeclared prefix "+prefix),
+		)
+	}
+	return QName{space, local}, nil
+}
+
+// lookupPrefix returns the namespace bound to prefix ("" for the default
+// namespace) by declarations of e or, if e was decoded, its ancestors.
+func (e *Element) lookupPrefix(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return NamespaceXML, true
+	}
+	name := xml.Name{Space: "xmlns", Local: prefix}
+	if prefix == "" {
+		name = xml.Name{Local: "xmlns"}
+	}
+	for _, ns := range [...][]xml.Attr{e.NS, e.scope} {
+		for i := len(ns) - 1; i >= 0; i-- {
+			if ns[i].Name == name {
+				return ns[i].Value, true
+			}
+		}
+	}
+	return "", prefix == ""
+}
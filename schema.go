@@ -0,0 +1,477 @@
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NamespaceXSD is the namespace of XML Schema definitions.
+const NamespaceXSD = "http://www.w3.org/2001/XMLSchema"
+
+// A Schema describes elements built from Go data structures. It is the
+// inverse of LoadStruct: for every added struct it knows the names, XSD types
+// and cardinality of the elements that MakeElement produces, and can write
+// them as an XSD document. Element trees (e.g. requests before they are sent)
+// can be checked against it using Validate.
+//
+// Besides the options known by MakeElement, the following soap tag options
+// are honored: required (minOccurs is 1 even for pointers and omitempty
+// fields) and enum=A|B|C (restricts the value to listed literals). Pointer
+// fields are nillable. Slices and arrays are described as sequences of item
+// elements, with minOccurs and maxOccurs given by the min and max options,
+// and the length, minLength, maxLength, pattern, totalDigits and
+// fractionDigits options are written as facets of xsd:restriction.
+type Schema struct {
+	TargetNamespace string
+
+	elems []*schemaElem
+	types []*schemaType
+	named map[string]*schemaType
+}
+
+type schemaElem struct {
+	name     string
+	xsdType  string      // built-in XSD type (without prefix) for simple content
+	ct       *schemaType // complex content
+	optional bool
+	nillable bool
+	enum     []string
+	facets   []xsdFacet     // restriction of simple content, except enum
+	pattern  *regexp.Regexp // compiled pattern facet
+
+	// minOccurs (if more than 1) and maxOccurs (if not 1) of repeated
+	// elements, e.g. items of arrays.
+	minOccurs, maxOccurs string
+
+	array bool // ct declares items of SOAP-ENC:Array
+}
+
+type schemaType struct {
+	name   string // empty for anonymous structs
+	goType reflect.Type
+	fields []*schemaElem
+}
+
+// NewSchema returns an empty schema for targetNS namespace (can be empty).
+func NewSchema(targetNS string) *Schema {
+	return &Schema{
+		TargetNamespace: targetNS,
+		named:           make(map[string]*schemaType),
+	}
+}
+
+// Add adds to s a top level element of given name, described by struct (or
+// pointer to struct) in a.
+func (s *Schema) Add(name string, a interface{}) error {
+	t := reflect.TypeOf(a)
+	if t == nil {
+		return errors.New("soap: can't add nil to schema")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return errors.New("soap: schema element should be a struct")
+	}
+	e := &schemaElem{name: name}
+	if err := s.describe(e, t, ""); err != nil {
+		return err
+	}
+	s.elems = append(s.elems, e)
+	return nil
+}
+
+func xsdScalarType(t reflect.Type) string {
+	if t == timeType {
+		return "dateTime"
+	}
+	if isUUIDType(t) {
+		return "string"
+	}
+	if typ := calendarTypes[t]; typ != "" {
+		return typ
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64:
+		return "long"
+	case reflect.Int32:
+		return "int"
+	case reflect.Int16:
+		return "short"
+	case reflect.Int8:
+		return "byte"
+	case reflect.Uint, reflect.Uint64:
+		return "unsignedLong"
+	case reflect.Uint32:
+		return "unsignedInt"
+	case reflect.Uint16:
+		return "unsignedShort"
+	case reflect.Uint8:
+		return "unsignedByte"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	}
+	return ""
+}
+
+// describe sets type of e according to t and facets of e according to
+// options of the field.
+func (s *Schema) describe(e *schemaElem, t reflect.Type, opts tagOptions) error {
+	if t == elementType {
+		e.xsdType = "anyType"
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if x := xsdScalarType(t); x != "" {
+		e.xsdType = x
+		if t.Kind() == reflect.String {
+			return e.setFacets(opts, "length", "minLength", "maxLength", "pattern")
+		}
+		return nil
+	}
+	if t == readerType {
+		e.xsdType = "base64Binary"
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			e.xsdType = "base64Binary"
+			return e.setFacets(opts, "length", "minLength", "maxLength")
+		}
+		return s.describeArray(e, t, opts)
+
+	case reflect.Struct:
+		ct, err := s.complexType(t)
+		if err != nil {
+			return err
+		}
+		e.ct = ct
+		return nil
+
+	case reflect.Map, reflect.Interface:
+		e.xsdType = "anyType"
+		return nil
+	}
+	return errors.New("soap: can't describe type " + t.String() + " in schema")
+}
+
+// describeArray sets e to an array of item elements of type t.Elem().
+func (s *Schema) describeArray(e *schemaElem, t reflect.Type, opts tagOptions) error {
+	item := &schemaElem{name: "item", optional: true, maxOccurs: "unbounded"}
+	switch t.Elem().Kind() {
+	case reflect.Ptr, reflect.Interface:
+		item.nillable = true
+	}
+	min, max := opts.Get("min"), opts.Get("max")
+	if t.Kind() == reflect.Array {
+		min, max = strconv.Itoa(t.Len()), strconv.Itoa(t.Len())
+	}
+	lo, err := parseLimit("min", min, 0)
+	if err != nil {
+		return err
+	}
+	if lo > 0 {
+		item.optional = false
+	}
+	if lo > 1 {
+		item.minOccurs = min
+	}
+	hi, err := parseLimit("max", max, -1)
+	if err != nil {
+		return err
+	}
+	if hi >= 0 {
+		item.maxOccurs = max
+	}
+	if hi == 1 {
+		item.maxOccurs = ""
+	}
+	if err := s.describe(item, t.Elem(), ""); err != nil {
+		return err
+	}
+	e.ct = &schemaType{goType: t, fields: []*schemaElem{item}}
+	e.array = true
+	return nil
+}
+
+// setFacets sets facets of e to values of given options.
+func (e *schemaElem) setFacets(opts tagOptions, names ...string) error {
+	for _, name := range names {
+		v := opts.Get(name)
+		if name == "pattern" {
+			v = opts.Rest(name)
+			if v == "" {
+				continue
+			}
+			// Patterns of XML Schema are anchored at both ends.
+			re, err := regexp.Compile("^(?:" + v + ")$")
+			if err != nil {
+				return errors.New("soap: bad pattern option: " + err.Error())
+			}
+			e.pattern = re
+		} else if _, err := parseLimit(name, v, 0); err != nil || v == "unbounded" {
+			return errors.New("soap: bad " + name + " option: " + v)
+		}
+		if v != "" {
+			e.facets = append(e.facets, xsdFacet{xml.Name{Local: "xsd:" + name}, v})
+		}
+	}
+	return nil
+}
+
+func (s *Schema) complexType(t reflect.Type) (*schemaType, error) {
+	if t.Name() != "" {
+		if ct := s.named[t.Name()]; ct != nil {
+			if ct.goType != t {
+				return nil, errors.New(
+					"soap: two different types named " + t.Name() + " in schema",
+				)
+			}
+			return ct, nil
+		}
+	}
+	ct := &schemaType{name: t.Name(), goType: t}
+	if ct.name != "" {
+		// Register before describing fields to allow recursive types.
+		s.named[ct.name] = ct
+		s.types = append(s.types, ct)
+	}
+	for _, tf := range cachedFields(t) {
+		name, opts := tf.name, tf.opts
+		f := &schemaElem{name: name}
+		if tf.typ.Kind() == reflect.Ptr {
+			f.nillable = true
+			f.optional = true
+		}
+		if tf.omitEmpty || tf.in {
+			f.optional = true
+		}
+		if opts.Contains("required") {
+			f.optional = false
+		}
+		if enum := opts.Get("enum"); enum != "" {
+			f.enum = strings.Split(enum, "|")
+		}
+		if tf.typ == elementsType {
+			// Items are written as repeated elements of the field name.
+			f.xsdType = "anyType"
+			f.optional = !opts.Contains("required")
+			f.maxOccurs = "unbounded"
+			ct.fields = append(ct.fields, f)
+			continue
+		}
+		if err := s.describe(f, tf.typ, opts); err != nil {
+			return nil, err
+		}
+		if tf.digits != nil {
+			f.xsdType = "decimal"
+			f.facets = nil
+			if err := f.setFacets(opts, "totalDigits", "fractionDigits"); err != nil {
+				return nil, err
+			}
+		}
+		ct.fields = append(ct.fields, f)
+	}
+	return ct, nil
+}
+
+type xsdSchema struct {
+	XMLName            xml.Name          `xml:"xsd:schema"`
+	XSD                string            `xml:"xmlns:xsd,attr"`
+	TNS                string            `xml:"xmlns:tns,attr,omitempty"`
+	TargetNamespace    string            `xml:"targetNamespace,attr,omitempty"`
+	ElementFormDefault string            `xml:"elementFormDefault,attr"`
+	Elements           []*xsdElement     `xml:"xsd:element"`
+	ComplexTypes       []*xsdComplexType `xml:"xsd:complexType"`
+}
+
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr,omitempty"`
+	MinOccurs   string          `xml:"minOccurs,attr,omitempty"`
+	MaxOccurs   string          `xml:"maxOccurs,attr,omitempty"`
+	Nillable    bool            `xml:"nillable,attr,omitempty"`
+	SimpleType  *xsdSimpleType  `xml:"xsd:simpleType"`
+	ComplexType *xsdComplexType `xml:"xsd:complexType"`
+}
+
+type xsdComplexType struct {
+	Name     string        `xml:"name,attr,omitempty"`
+	Elements []*xsdElement `xml:"xsd:sequence>xsd:element"`
+}
+
+type xsdSimpleType struct {
+	Restriction xsdRestriction `xml:"xsd:restriction"`
+}
+
+type xsdRestriction struct {
+	Base   string     `xml:"base,attr"`
+	Facets []xsdFacet `xml:",any"`
+}
+
+type xsdFacet struct {
+	XMLName xml.Name
+	Value   string `xml:"value,attr"`
+}
+
+func (s *Schema) typeRef(name string) string {
+	if s.TargetNamespace != "" {
+		return "tns:" + name
+	}
+	return name
+}
+
+func (s *Schema) xsdElement(e *schemaElem) *xsdElement {
+	x := &xsdElement{
+		Name:      e.name,
+		Nillable:  e.nillable,
+		MinOccurs: e.minOccurs,
+		MaxOccurs: e.maxOccurs,
+	}
+	if e.optional {
+		x.MinOccurs = "0"
+	}
+	switch {
+	case e.ct == nil && (len(e.enum) != 0 || len(e.facets) != 0):
+		x.SimpleType = &xsdSimpleType{xsdRestriction{
+			Base:   "xsd:" + e.xsdType,
+			Facets: append([]xsdFacet(nil), e.facets...),
+		}}
+		for _, v := range e.enum {
+			x.SimpleType.Restriction.Facets = append(
+				x.SimpleType.Restriction.Facets,
+				xsdFacet{xml.Name{Local: "xsd:enumeration"}, v},
+			)
+		}
+	case e.ct == nil:
+		x.Type = "xsd:" + e.xsdType
+	case e.ct.name == "":
+		x.ComplexType = s.xsdComplexType(e.ct)
+	default:
+		x.Type = s.typeRef(e.ct.name)
+	}
+	return x
+}
+
+func (s *Schema) xsdComplexType(ct *schemaType) *xsdComplexType {
+	x := &xsdComplexType{Name: ct.name}
+	for _, f := range ct.fields {
+		x.Elements = append(x.Elements, s.xsdElement(f))
+	}
+	return x
+}
+
+// WriteTo writes s as an XSD document to w.
+func (s *Schema) WriteTo(w io.Writer) (int64, error) {
+	x := &xsdSchema{
+		XSD:                NamespaceXSD,
+		TargetNamespace:    s.TargetNamespace,
+		ElementFormDefault: "unqualified",
+	}
+	if s.TargetNamespace != "" {
+		x.TNS = s.TargetNamespace
+	}
+	for _, e := range s.elems {
+		x.Elements = append(x.Elements, s.xsdElement(e))
+	}
+	for _, ct := range s.types {
+		x.ComplexTypes = append(x.ComplexTypes, s.xsdComplexType(ct))
+	}
+	buf, err := xml.MarshalIndent(x, "", "\t")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(buf)
+	return int64(n + m), err
+}
+
+var registry struct {
+	sync.RWMutex
+	elems map[xml.Name]*schemaElem
+}
+
+// RegisterSchema makes top level elements of s known to Value and LoadStruct,
+// which use them to determine types of elements without xsi:type attribute
+// (typical for document/literal services). Elements are matched by name in
+// the TargetNamespace of s, so schemas of different namespaces can declare
+// elements of the same local name. Elements registered later replace earlier
+// ones of the same name. Elements added to s after registration aren't
+// registered. Only schemas built from Go types (see Schema.Add) can be
+// registered; XSD and WSDL documents aren't parsed.
+func RegisterSchema(s *Schema) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.elems == nil {
+		registry.elems = make(map[xml.Name]*schemaElem)
+	}
+	for _, e := range s.elems {
+		registry.elems[xml.Name{Space: s.TargetNamespace, Local: e.name}] = e
+	}
+}
+
+func lookupSchemaElem(name xml.Name) *schemaElem {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.elems[name]
+}
+
+// typeOf returns the SOAP type of e without namespace prefix. If e has no
+// xsi:type attribute the type declared by d is returned.
+func (d *schemaElem) typeOf(e *Element) string {
+	if e.Type != "" || d == nil {
+		return skipNS(e.Type)
+	}
+	if d.array {
+		return "Array"
+	}
+	if d.ct != nil {
+		return "Struct"
+	}
+	if d.xsdType == "anyType" {
+		return ""
+	}
+	return d.xsdType
+}
+
+// occurs returns the minimum and maximum (-1 if unbounded) number of
+// occurrences of elements declared by d.
+func (d *schemaElem) occurs() (min, max int) {
+	min, max = 1, 1
+	if d.optional {
+		min = 0
+	} else if d.minOccurs != "" {
+		min, _ = strconv.Atoi(d.minOccurs)
+	}
+	if d.maxOccurs != "" {
+		max, _ = parseLimit("maxOccurs", d.maxOccurs, 1)
+	}
+	return min, max
+}
+
+// field returns the declaration of child element of given name or nil if d is
+// nil or doesn't declare such child.
+func (d *schemaElem) field(name string) *schemaElem {
+	if d == nil || d.ct == nil {
+		return nil
+	}
+	return d.ct.field(name)
+}
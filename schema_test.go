@@ -0,0 +1,106 @@
+package soap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type schemaLine struct {
+	SKU   string  `soap:",pattern=[A-Z]{3}-[0-9]+"`
+	Price float64 `soap:",totalDigits=8,fractionDigits=2"`
+}
+
+type schemaOrder struct {
+	ID    string       `soap:",minLength=1,maxLength=16"`
+	Code  string       `soap:",length=4,enum=ABCD|EFGH"`
+	Lines []schemaLine `soap:",min=1,max=3"`
+	Tags  []string     `soap:",omitempty"`
+	Sig   []byte       `soap:",maxLength=64"`
+	Pair  [2]int
+	Any   []*Element
+}
+
+func TestSchemaSlices(t *testing.T) {
+	s := NewSchema("")
+	if err := s.Add("Order", schemaOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if _, err := s.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	xsd := b.String()
+	for _, want := range []string{
+		`<xsd:element name="item" type="schemaLine" maxOccurs="3"></xsd:element>`,
+		`<xsd:element name="item" type="xsd:string" minOccurs="0" maxOccurs="unbounded"></xsd:element>`,
+		`<xsd:element name="item" type="xsd:long" minOccurs="2" maxOccurs="2"></xsd:element>`,
+		`<xsd:element name="Any" type="xsd:anyType" minOccurs="0" maxOccurs="unbounded"></xsd:element>`,
+		`<xsd:restriction base="xsd:base64Binary">`,
+		`<xsd:maxLength value="64"></xsd:maxLength>`,
+		`<xsd:minLength value="1"></xsd:minLength>`,
+		`<xsd:length value="4"></xsd:length>`,
+		`<xsd:enumeration value="EFGH"></xsd:enumeration>`,
+		`<xsd:pattern value="[A-Z]{3}-[0-9]+"></xsd:pattern>`,
+		`<xsd:restriction base="xsd:decimal">`,
+		`<xsd:totalDigits value="8"></xsd:totalDigits>`,
+		`<xsd:fractionDigits value="2"></xsd:fractionDigits>`,
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(xsd), " "), want) {
+			t.Errorf("no %s in:\n%s", want, xsd)
+		}
+	}
+
+	order := schemaOrder{
+		ID: "1", Code: "ABCD", Sig: []byte{1},
+		Lines: []schemaLine{{"ABC-1", 1.5}, {"ABC-2", 2}},
+	}
+	e := MakeElement("Order", order)
+	if vs := s.Validate(e); vs != nil {
+		t.Errorf("valid order: %v", vs)
+	}
+	lines := e.child("Lines")
+	lines.SetChildren(append(lines.Children, lines.Children[0].Clone(), lines.Children[1].Clone()))
+	vs := s.Validate(e)
+	if len(vs) != 1 || vs[0].Msg != "element occurs more than 3 times" {
+		t.Errorf("too many lines: %v", vs)
+	}
+	lines.SetChildren(nil)
+	vs = s.Validate(e)
+	if len(vs) != 1 || vs[0].Path != "Order/Lines/item" {
+		t.Errorf("no lines: %v", vs)
+	}
+
+	if err := NewSchema("").Add("X", struct {
+		A []int `soap:",max=x"`
+	}{}); err == nil {
+		t.Error("no error for bad max option")
+	}
+}
+
+func TestRegisterSchemaNamespaces(t *testing.T) {
+	a, b := NewSchema("urn:reg-a"), NewSchema("urn:reg-b")
+	if err := a.Add("RegOrder", struct{ ID int64 }{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add("RegOrder", struct{ ID string }{}); err != nil {
+		t.Fatal(err)
+	}
+	RegisterSchema(a)
+	RegisterSchema(b)
+	for ns, want := range map[string]interface{}{"urn:reg-a": int64(7), "urn:reg-b": "7"} {
+		e, err := new(Decoder).Decode(strings.NewReader(
+			`<RegOrder xmlns="` + ns + `"><ID>7</ID></RegOrder>`,
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := e.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id := v.(map[string]interface{})["ID"]; id != want {
+			t.Errorf("%s: ID %#v, want %#v", ns, id, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package soap
+
+import (
+	"sort"
+	"strings"
+)
+
+// snapshotMask replaces values masked in snapshots.
+const snapshotMask = "***"
+
+// snapshot returns a copy of the tree of e in the form written by Encoder
+// with Snapshot set: with attributes sorted by name, namespace declarations
+// dropped (so the encoder declares its own prefixes), white space between
+// elements removed (outside of xml:space="preserve" scopes) and values of
+// elements and attributes named in mask replaced. Order of elements and
+// empty elements are kept, so snapshots show changes of them.
+func snapshot(e *Element, mask []string) *Element {
+	c := e.Clone()
+	masked := make(map[string]bool, len(mask))
+	for _, m := range mask {
+		masked[m] = true
+	}
+	type frame struct {
+		e        *Element
+		preserve bool
+	}
+	stack := []frame{{c, false}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		e := f.e
+		e.expand()
+		preserve := e.preserveSpace(f.preserve)
+		e.NS = nil
+		if !preserve {
+			trimSpaceBetween(e)
+		}
+		attrs := e.Attrs
+		sort.SliceStable(attrs, func(i, j int) bool {
+			if attrs[i].Name.Space != attrs[j].Name.Space {
+				return attrs[i].Name.Space < attrs[j].Name.Space
+			}
+			return attrs[i].Name.Local < attrs[j].Name.Local
+		})
+		for i := range attrs {
+			if masked[attrs[i].Name.Local] {
+				attrs[i].Value = snapshotMask
+			}
+		}
+		if masked[e.XMLName.Local] && !e.Nil {
+			e.Text, e.stream, e.Content = snapshotMask, nil, nil
+			e.SetChildren(nil)
+			continue
+		}
+		for _, ch := range e.Children {
+			if ch != nil {
+				stack = append(stack, frame{ch, preserve})
+			}
+		}
+	}
+	return c
+}
+
+// trimSpaceBetween removes white space between children of e: its text if
+// it has children and white space text nodes of its mixed content.
+func trimSpaceBetween(e *Element) {
+	if !e.hasChildren() {
+		return
+	}
+	e.Text = strings.TrimSpace(e.Text)
+	content := e.Content[:0]
+	for _, n := range e.Content {
+		if n.Elem == nil && !n.Comment && n.Target == "" && strings.TrimSpace(n.Text) == "" {
+			continue
+		}
+		content = append(content, n)
+	}
+	e.Content = content
+}
+
+// appendTree works like the appendTree function but writes snapshots of
+// trees if enc.Snapshot is set.
+func (enc *Encoder) appendTree(b []byte, e *Element, xsi string, s *streamer) []byte {
+	if !enc.Snapshot || e == nil {
+		return appendTree(b, e, xsi, s)
+	}
+	c := snapshot(e, enc.Mask)
+	b = appendTree(b, c, xsi, s)
+	c.Release()
+	return b
+}
@@ -0,0 +1,40 @@
+package soap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	docs := []string{
+		`<p:R xmlns:p="urn:x" z="1" a="2"><p:B> x </p:B><p:Created>2020</p:Created><p:E/><p:T xml:space="preserve"> <p:I/> </p:T></p:R>`,
+		`<q:R xmlns:q="urn:x" a="2" z="1">
+			<q:B> x </q:B>
+			<q:Created>2021</q:Created>
+			<q:E/>
+			<q:T xml:space="preserve"> <q:I/> </q:T>
+		</q:R>`,
+	}
+	want := `<R xmlns="urn:x" a="2" z="1"><B> x </B><Created>***</Created><E/>` +
+		`<T xml:space="preserve"> <I/> </T></R>`
+	for _, doc := range docs {
+		e, err := NewDecoder(WithMixed(true)).Decode(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if err := NewEncoder(&b, WithSnapshot("Created")).Encode(e); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != want {
+			t.Errorf("got  %s\nwant %s", b.String(), want)
+		}
+	}
+	// Order of elements matters.
+	e, _ := new(Decoder).Decode(strings.NewReader(`<R><B/><A/></R>`))
+	b := NewEncoder(nil, WithSnapshot()).AppendElement(nil, e)
+	if string(b) != `<R><B/><A/></R>` {
+		t.Errorf("got %s", b)
+	}
+}
@@ -0,0 +1,211 @@
+// Package soaptest provides utilities for testing code that builds or
+// consumes soap.Element trees.
+//
+// Elements are compared structurally: element names with namespaces,
+// attributes, xsi:type and xsi:nil, child order and text with surrounding
+// white space trimmed. This makes tests independent of attribute order,
+// namespace prefixes and indentation of the XML they were parsed from.
+package soaptest
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ziutek/soap"
+)
+
+var update = flag.Bool(
+	"soaptest.update", false, "rewrite golden files instead of comparing",
+)
+
+// Diff returns descriptions of all differences between got and want (nil if
+// they are equal).
+func Diff(got, want *soap.Element) []string {
+	switch {
+	case got == nil && want == nil:
+		return nil
+	case got == nil:
+		return []string{want.XMLName.Local + ": got no element"}
+	case want == nil:
+		return []string{got.XMLName.Local + ": want no element"}
+	}
+	got.Expand()
+	want.Expand()
+	return diff(nil, want.XMLName.Local, got, want)
+}
+
+func diff(ds []string, path string, got, want *soap.Element) []string {
+	if got == nil || want == nil {
+		if got != want {
+			ds = append(ds, fmt.Sprintf("%s: got %v, want %v", path, got, want))
+		}
+		return ds
+	}
+	if got.XMLName != want.XMLName {
+		ds = append(ds, fmt.Sprintf(
+			"%s: got element %s, want %s",
+			path, name(got.XMLName), name(want.XMLName),
+		))
+	}
+	ds = diffAttrs(ds, path, got.Attrs, want.Attrs)
+	if skipNS(got.Type) != skipNS(want.Type) {
+		ds = append(ds, fmt.Sprintf(
+			"%s: got type '%s', want '%s'", path, got.Type, want.Type,
+		))
+	}
+	if got.Nil != want.Nil {
+		ds = append(ds, fmt.Sprintf(
+			"%s: got nil=%t, want nil=%t", path, got.Nil, want.Nil,
+		))
+	}
+	gt, wt := strings.TrimSpace(got.Text), strings.TrimSpace(want.Text)
+	if gt != wt {
+		ds = append(ds, fmt.Sprintf(
+			"%s: got text '%s', want '%s'", path, gt, wt,
+		))
+	}
+	n := len(got.Children)
+	if len(want.Children) != n {
+		ds = append(ds, fmt.Sprintf(
+			"%s: got %d children, want %d", path, n, len(want.Children),
+		))
+		if len(want.Children) < n {
+			n = len(want.Children)
+		}
+	}
+	for i := 0; i < n; i++ {
+		ds = diff(
+			ds, path+"/"+want.Children[i].XMLName.Local,
+			got.Children[i], want.Children[i],
+		)
+	}
+	return ds
+}
+
+// diffAttrs appends differences between attributes got and want, compared
+// regardless of their order.
+func diffAttrs(ds []string, path string, got, want []xml.Attr) []string {
+	values := make(map[xml.Name]string, len(got))
+	for _, a := range got {
+		values[a.Name] = a.Value
+	}
+	for _, a := range want {
+		v, ok := values[a.Name]
+		switch {
+		case !ok:
+			ds = append(ds, fmt.Sprintf(
+				"%s: no attribute %s, want '%s'", path, name(a.Name), a.Value,
+			))
+		case v != a.Value:
+			ds = append(ds, fmt.Sprintf(
+				"%s: got attribute %s='%s', want '%s'",
+				path, name(a.Name), v, a.Value,
+			))
+		}
+		delete(values, a.Name)
+	}
+	for _, a := range got {
+		if _, ok := values[a.Name]; ok {
+			ds = append(ds, fmt.Sprintf(
+				"%s: unexpected attribute %s='%s'", path, name(a.Name), a.Value,
+			))
+		}
+	}
+	return ds
+}
+
+// name returns n as {space}local or local if n has no namespace.
+func name(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return "{" + n.Space + "}" + n.Local
+}
+
+func skipNS(s string) string {
+	if i := strings.IndexByte(s, ':'); i != -1 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// AssertEqualElements reports an error for each difference between got and
+// want.
+func AssertEqualElements(tb testing.TB, got, want *soap.Element) {
+	tb.Helper()
+	for _, d := range Diff(got, want) {
+		tb.Error(d)
+	}
+}
+
+// Parse parses XML document in buf into an Element tree.
+func Parse(buf []byte) (*soap.Element, error) {
+	e := new(soap.Element)
+	if err := xml.Unmarshal(buf, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// LoadFixture parses XML file into an Element tree. It stops the test on
+// error.
+func LoadFixture(tb testing.TB, path string) *soap.Element {
+	tb.Helper()
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	e, err := Parse(buf)
+	if err != nil {
+		tb.Fatalf("%s: %v", path, err)
+	}
+	return e
+}
+
+// Body returns the first element of the Body of envelope e or nil if e isn't
+// an envelope or has an empty Body.
+func Body(e *soap.Element) *soap.Element {
+	if e.XMLName.Local != "Envelope" {
+		return nil
+	}
+	for _, c := range e.Children {
+		if c.XMLName.Local == "Body" && len(c.Children) != 0 {
+			return c.Children[0]
+		}
+	}
+	return nil
+}
+
+// LoadBody loads an envelope file using LoadFixture and returns the first
+// element of its Body.
+func LoadBody(tb testing.TB, path string) *soap.Element {
+	tb.Helper()
+	e := Body(LoadFixture(tb, path))
+	if e == nil {
+		tb.Fatalf("%s: no SOAP Body content", path)
+	}
+	return e
+}
+
+// AssertGolden compares got with the tree stored in golden file path. If the
+// test binary was run with -soaptest.update flag, the file is rewritten with
+// got instead.
+func AssertGolden(tb testing.TB, path string, got *soap.Element) {
+	tb.Helper()
+	if *update {
+		buf, err := xml.MarshalIndent(got, "", "\t")
+		if err != nil {
+			tb.Fatal(err)
+		}
+		buf = append(buf, '\n')
+		if err := os.WriteFile(path, buf, 0644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+	AssertEqualElements(tb, got, LoadFixture(tb, path))
+}
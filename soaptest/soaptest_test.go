@@ -0,0 +1,73 @@
+package soaptest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ziutek/soap"
+)
+
+func parse(t *testing.T, s string) *soap.Element {
+	t.Helper()
+	e, err := Parse([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestDiff(t *testing.T) {
+	want := parse(t, `<a:R xmlns:a="urn:a" x="1" y="2"><A> 1 </A></a:R>`)
+	cases := []struct {
+		got string
+		ds  []string
+	}{
+		{`<b:R xmlns:b="urn:a" y="2" x="1">
+			<A>1</A>
+		</b:R>`, nil},
+		{`<b:R xmlns:b="urn:b" x="1" y="2"><A>1</A></b:R>`, []string{
+			"R: got element {urn:b}R, want {urn:a}R",
+		}},
+		{`<a:R xmlns:a="urn:a" x="2" z="3"><A>1</A></a:R>`, []string{
+			"R: got attribute x='2', want '1'",
+			"R: no attribute y, want '2'",
+			"R: unexpected attribute z='3'",
+		}},
+		{`<a:R xmlns:a="urn:a" x="1" y="2"><A>2</A><B/></a:R>`, []string{
+			"R: got 2 children, want 1",
+			"R/A: got text '2', want '1'",
+		}},
+	}
+	for _, c := range cases {
+		if ds := Diff(parse(t, c.got), want); !reflect.DeepEqual(ds, c.ds) {
+			t.Errorf("got %q\nwant %q", ds, c.ds)
+		}
+	}
+	if ds := Diff(nil, want); len(ds) != 1 {
+		t.Errorf("Diff(nil, want): %q", ds)
+	}
+	if ds := Diff(want, nil); len(ds) != 1 {
+		t.Errorf("Diff(got, nil): %q", ds)
+	}
+	if ds := Diff(nil, nil); ds != nil {
+		t.Errorf("Diff(nil, nil): %q", ds)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.xml")
+	got := soap.MakeElement("S", struct{ A int }{1})
+	*update = true
+	AssertGolden(t, path, got)
+	*update = false
+	AssertGolden(t, path, got)
+
+	env := parse(t, `<Envelope><Header/><Body><R/></Body></Envelope>`)
+	if b := Body(env); b == nil || b.XMLName.Local != "R" {
+		t.Errorf("Body: %v", b)
+	}
+	if Body(got) != nil {
+		t.Error("Body of a non-envelope")
+	}
+}
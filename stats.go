@@ -0,0 +1,33 @@
+package soap
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// Stats are counters of the package, accumulated since the program started.
+type Stats struct {
+	Decoded      int64 // documents decoded by Decoder.Decode
+	DecodeErrors int64 // failed Decoder.Decode calls
+	Allocated    int64 // elements allocated because the pool was empty
+	Released     int64 // elements returned to the pool by Release
+}
+
+var stats Stats
+
+// ReadStats returns the current values of counters.
+func ReadStats() Stats {
+	return Stats{
+		Decoded:      atomic.LoadInt64(&stats.Decoded),
+		DecodeErrors: atomic.LoadInt64(&stats.DecodeErrors),
+		Allocated:    atomic.LoadInt64(&stats.Allocated),
+		Released:     atomic.LoadInt64(&stats.Released),
+	}
+}
+
+// PublishExpvar publishes counters returned by ReadStats as expvar variable
+// of given name (e.g. "soap"), so they are served at /debug/vars. It panics
+// if the name is already used, so call it once.
+func PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return ReadStats() }))
+}
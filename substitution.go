@@ -0,0 +1,116 @@
+package soap
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var substitutions struct {
+	sync.RWMutex
+	types   map[string]reflect.Type // member element name -> Go type
+	members map[string][]string     // head element name -> member names
+}
+
+// RegisterSubstitute registers element member of the substitution group of
+// element head and the type of v as its Go type (e.g. &PremiumAccount{}).
+// Load sets interface fields named head to values of that type when the
+// member element appears in place of the head element, and MakeElement
+// writes values of that type in interface fields named head as member
+// elements. The head element itself can be registered as a member too.
+func RegisterSubstitute(head, member string, v interface{}) {
+	substitutions.Lock()
+	defer substitutions.Unlock()
+	if substitutions.types == nil {
+		substitutions.types = make(map[string]reflect.Type)
+		substitutions.members = make(map[string][]string)
+	}
+	if _, ok := substitutions.types[member]; !ok {
+		substitutions.members[head] = append(substitutions.members[head], member)
+	}
+	substitutions.types[member] = reflect.TypeOf(v)
+}
+
+// substituteOf returns the first child of e that is a registered member of
+// the substitution group of head or nil.
+func (e *Element) substituteOf(head string) *Element {
+	substitutions.RLock()
+	members := substitutions.members[head]
+	substitutions.RUnlock()
+	for _, m := range members {
+		if c := e.child(m); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// memberName returns the name of the registered member of the substitution
+// group of head that has the type of the value in interface v, or head.
+func memberName(head string, v reflect.Value) string {
+	if v.IsNil() {
+		return head
+	}
+	t := v.Elem().Type()
+	substitutions.RLock()
+	defer substitutions.RUnlock()
+	for _, m := range substitutions.members[head] {
+		if substitutions.types[m] == t {
+			return m
+		}
+	}
+	return head
+}
+
+var memberLoaders sync.Map // map[reflect.Type]loadFunc
+
+// memberLoader returns the loadFunc of registered member type t, building it
+// only once per type.
+func memberLoader(t reflect.Type) loadFunc {
+	if f, ok := memberLoaders.Load(t); ok {
+		return f.(loadFunc)
+	}
+	f, _ := memberLoaders.LoadOrStore(t, fieldLoader(t, ""))
+	return f.(loadFunc)
+}
+
+// substituteLoader returns the loadFunc of interface fields of type t.
+func substituteLoader(t reflect.Type) loadFunc {
+	return func(item *Element, v reflect.Value, o *loadOpts) error {
+		if item.Nil {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		substitutions.RLock()
+		typ := substitutions.types[item.XMLName.Local]
+		substitutions.RUnlock()
+		if typ == nil && t.NumMethod() == 0 {
+			// interface{} holds the value returned by Value, converted with
+			// the settings of the Load call. Unless mode is Strict, untyped
+			// simple elements hold their text.
+			dec := Decoder{Location: o.loc, UTC: o.utc}
+			a, err := dec.Value(item)
+			if err != nil && o.mode != Strict && item.Type == "" && !item.hasChildren() {
+				a, err = item.Text, nil
+			}
+			if err == nil && a != nil {
+				v.Set(reflect.ValueOf(a))
+			}
+			return err
+		}
+		if typ == nil {
+			return errors.New(
+				"soap: no type registered for element " + item.XMLName.Local,
+			)
+		}
+		if !typ.AssignableTo(t) {
+			return errors.New("soap: can't assign " + typ.String() + " to " + t.String())
+		}
+		p := reflect.New(typ)
+		if err := memberLoader(typ)(item, p.Elem(), o); err != nil {
+			return err
+		}
+		v.Set(p.Elem())
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+package soap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type subAccount interface{ account() }
+
+type subPremium struct{ Level int64 }
+
+func (subPremium) account() {}
+
+func TestSubstituteLoadOpts(t *testing.T) {
+	type S struct{ V interface{} }
+	e, err := new(Decoder).Decode(strings.NewReader(
+		`<S xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">` +
+			`<V xsi:type="xsd:dateTime">2020-01-02T03:04:05</V></S>`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("X", 3600)
+	for _, dec := range []*Decoder{{Location: loc}, {Location: loc, UTC: true}} {
+		var s S
+		if err := dec.Load(e, &s, Coerce); err != nil {
+			t.Fatal(err)
+		}
+		want := time.Date(2020, 1, 2, 3, 4, 5, 0, loc)
+		if dec.UTC {
+			want = want.UTC()
+		}
+		if v, _ := s.V.(time.Time); !v.Equal(want) || v.Location() != want.Location() {
+			t.Errorf("UTC %t: %v, want %v", dec.UTC, s.V, want)
+		}
+	}
+
+	e, err = new(Decoder).Decode(strings.NewReader(`<S><V>x</V></S>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s S
+	if err := e.Load(&s, Strict); err == nil {
+		t.Error("untyped element loaded in Strict mode")
+	}
+	if err := e.Load(&s, Coerce); err != nil || s.V != "x" {
+		t.Errorf("Coerce: %#v, %v", s.V, err)
+	}
+}
+
+func TestSubstituteMember(t *testing.T) {
+	RegisterSubstitute("SubAccount", "SubPremium", subPremium{})
+	type S struct{ SubAccount subAccount }
+	e := MakeElement("S", S{subPremium{3}})
+	for i := 0; i < 2; i++ {
+		var s S
+		if err := e.Load(&s, Strict); err != nil {
+			t.Fatal(err)
+		}
+		if s.SubAccount != (subPremium{3}) {
+			t.Errorf("got %#v", s.SubAccount)
+		}
+	}
+	if _, ok := memberLoaders.Load(reflect.TypeOf(subPremium{})); !ok {
+		t.Error("member loader isn't cached")
+	}
+}
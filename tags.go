@@ -0,0 +1,148 @@
+package soap
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// tagOptions is the string following a comma in a struct field's "soap" tag,
+// or the empty string.
+type tagOptions string
+
+// parseTag splits a struct field's soap tag into its name and comma-separated
+// options.
+func parseTag(tag string) (string, tagOptions) {
+	if i := strings.IndexRune(tag, ','); i != -1 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, ""
+}
+
+// fieldTag returns the soap tag of a struct field. Fields without soap tag
+// use their encoding/xml tag, so structs generated for encoding/xml work
+// as is: the local name and the omitempty option are honored, and fields
+// that don't map to a child element (attr, chardata, innerxml, comment, any
+// and a>b paths) are skipped. XMLName fields are handled by structXMLName.
+func fieldTag(tag reflect.StructTag) string {
+	if t, ok := tag.Lookup("soap"); ok {
+		return t
+	}
+	t, ok := tag.Lookup("xml")
+	if !ok {
+		return ""
+	}
+	name, opts := parseTag(t)
+	for _, o := range [...]string{"attr", "chardata", "innerxml", "comment", "any"} {
+		if opts.Contains(o) {
+			return "-"
+		}
+	}
+	if strings.Contains(name, ">") {
+		return "-"
+	}
+	if i := strings.LastIndexByte(name, ' '); i != -1 {
+		name = name[i+1:] // drop namespace
+	}
+	if opts.Contains("omitempty") {
+		return name + ",omitempty"
+	}
+	return name
+}
+
+var xmlNameType = reflect.TypeOf(xml.Name{})
+
+// An xmlNameField is the XMLName field of a struct used with encoding/xml.
+type xmlNameField struct {
+	index int      // -1 if the struct has none
+	name  xml.Name // from the xml tag
+}
+
+var xmlNameCache sync.Map // map[reflect.Type]xmlNameField
+
+// structXMLName returns the XMLName field of struct type t. It isn't a child
+// element: its tag, e.g. `xml:"urn:x Order"`, gives the namespace of the
+// struct element and the name of fields of type t that have no tag.
+func structXMLName(t reflect.Type) xmlNameField {
+	if xn, ok := xmlNameCache.Load(t); ok {
+		return xn.(xmlNameField)
+	}
+	xn := xmlNameField{index: -1}
+	if f, ok := t.FieldByName("XMLName"); ok && len(f.Index) == 1 && f.Type == xmlNameType {
+		xn.index = f.Index[0]
+		name, _ := parseTag(f.Tag.Get("xml"))
+		if i := strings.LastIndexByte(name, ' '); i != -1 {
+			xn.name.Space, name = name[:i], name[i+1:]
+		}
+		xn.name.Local = name
+	}
+	xmlNameCache.Store(t, xn)
+	return xn
+}
+
+// setStructName sets the namespace of e made of struct v from the XMLName
+// field of v (its value if set, else its tag).
+func setStructName(e *Element, v reflect.Value) {
+	xn := structXMLName(v.Type())
+	if xn.index < 0 {
+		return
+	}
+	name := xn.name
+	if n := v.Field(xn.index).Interface().(xml.Name); n.Local != "" {
+		name = n
+	}
+	if name.Space != "" {
+		e.XMLName.Space = name.Space
+	}
+}
+
+// Contains reports whether a comma-separated list of options contains a
+// particular option flag.
+func (o tagOptions) Contains(name string) bool {
+	_, ok := o.lookup(name)
+	return ok
+}
+
+// Get returns the value of option in the form name=value. It returns an empty
+// string if there is no such option.
+func (o tagOptions) Get(name string) string {
+	v, _ := o.lookup(name)
+	return v
+}
+
+// Rest returns the value of option name=value that ends the list, so value
+// can contain commas. It returns an empty string if there is no such option.
+func (o tagOptions) Rest(name string) string {
+	s := string(o)
+	for s != "" {
+		if strings.HasPrefix(s, name+"=") {
+			return s[len(name)+1:]
+		}
+		i := strings.IndexRune(s, ',')
+		if i == -1 {
+			break
+		}
+		s = s[i+1:]
+	}
+	return ""
+}
+
+func (o tagOptions) lookup(name string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var opt string
+		if i := strings.IndexRune(s, ','); i != -1 {
+			opt, s = s[:i], s[i+1:]
+		} else {
+			opt, s = s, ""
+		}
+		if opt == name {
+			return "", true
+		}
+		if strings.HasPrefix(opt, name) && opt[len(name)] == '=' {
+			return opt[len(name)+1:], true
+		}
+	}
+	return "", false
+}
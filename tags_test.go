@@ -0,0 +1,56 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type xmlLine struct {
+	XMLName xml.Name `xml:"urn:x Line"`
+	Qty     int64    `xml:"qty"`
+}
+
+type xmlOrder struct {
+	XMLName xml.Name `xml:"urn:x Order"`
+	ID      string   `xml:"id"`
+	Item    xmlLine
+}
+
+func TestXMLNameField(t *testing.T) {
+	o := xmlOrder{ID: "7", Item: xmlLine{Qty: 2}}
+	e := MakeElement("Order", o)
+	if e.XMLName.Space != "urn:x" || len(e.Children) != 2 {
+		t.Fatalf("made %+v", e)
+	}
+	if c := e.Children[1]; c.XMLName != (xml.Name{Space: "urn:x", Local: "Line"}) {
+		t.Fatalf("child named %v", c.XMLName)
+	}
+	c, err := CompileCodec[xmlOrder]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ce, err := c.Encode("Order", &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(ce, e) {
+		t.Fatal("Codec.Encode differs from MakeElement")
+	}
+	for _, mode := range []DecodeMode{Strict, Coerce} {
+		var r xmlOrder
+		if err := e.Load(&r, mode); err != nil {
+			t.Fatal(err)
+		}
+		if r.ID != "7" || r.Item.Qty != 2 || r.XMLName.Local != "Order" {
+			t.Fatalf("loaded %+v", r)
+		}
+	}
+	out := new(Encoder).AppendElement(nil, e)
+	var x xmlOrder
+	if err := xml.Unmarshal(out, &x); err != nil {
+		t.Fatal(err)
+	}
+	if x.ID != "7" || x.Item.Qty != 2 {
+		t.Fatalf("encoding/xml read %+v", x)
+	}
+}
@@ -0,0 +1,160 @@
+package soap
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// A Template is a document (usually a whole envelope) captured as is, e.g.
+// from SoapUI or vendor docs, with slots for parameters written as ${name}.
+// An element whose whole text is a slot is replaced by the parameter made
+// into an element (keeping the name and attributes of the slot element), so
+// structs, times and other typed values can be substituted. Slots inside
+// other texts and attribute values are replaced by the text of their
+// parameters. Templates can be executed concurrently.
+type Template struct {
+	// Profile used to make parameters into elements. If nil, DefaultProfile
+	// is used.
+	Profile *Profile
+
+	root *Element
+}
+
+// ParseTemplate decodes the document read from r as a Template.
+func ParseTemplate(r io.Reader) (*Template, error) {
+	root, err := new(Decoder).Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{root: root}, nil
+}
+
+// Execute returns a new tree of t with slots replaced by params. Values of
+// type *Element are substituted as copies. All slots must have parameters.
+func (t *Template) Execute(params map[string]interface{}) (*Element, error) {
+	p := t.Profile
+	if p == nil {
+		p = DefaultProfile
+	}
+	root := t.root.Clone()
+	stack := []*Element{root}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for i := range e.Attrs {
+			v, err := expandSlots(p, e.Attrs[i].Value, params)
+			if err != nil {
+				root.Release()
+				return nil, err
+			}
+			e.Attrs[i].Value = v
+		}
+		if name, ok := slotName(strings.TrimSpace(e.Text)); ok && !e.hasChildren() {
+			if err := p.fillSlot(e, name, params); err != nil {
+				root.Release()
+				return nil, err
+			}
+			continue
+		}
+		v, err := expandSlots(p, e.Text, params)
+		if err != nil {
+			root.Release()
+			return nil, err
+		}
+		e.Text = v
+		for _, c := range e.Children {
+			if c != nil {
+				stack = append(stack, c)
+			}
+		}
+	}
+	return root, nil
+}
+
+// fillSlot sets the content of slot element e to params[name].
+func (p *Profile) fillSlot(e *Element, name string, params map[string]interface{}) error {
+	v, ok := params[name]
+	if !ok {
+		return errors.New("soap: no parameter for template slot " + name)
+	}
+	m, ok := v.(*Element)
+	if !ok {
+		var err error
+		if m, err = p.Marshal(e.XMLName.Local, v); err != nil {
+			return err
+		}
+		defer m.Release()
+	}
+	attrs, ns, typ := e.Attrs, e.NS, e.Type
+	e.setContent(m)
+	e.Attrs = append(attrs, e.Attrs...)
+	e.NS = append(ns, e.NS...)
+	if e.Type == "" {
+		e.Type = typ
+	}
+	return nil
+}
+
+// slotName returns name if s is ${name}.
+func slotName(s string) (string, bool) {
+	if !strings.HasPrefix(s, "${") || !strings.HasSuffix(s, "}") {
+		return "", false
+	}
+	name := s[2 : len(s)-1]
+	if name == "" || strings.ContainsAny(name, "${}") {
+		return "", false
+	}
+	return name, true
+}
+
+// expandSlots replaces slots in s with texts of their parameters.
+func expandSlots(p *Profile, s string, params map[string]interface{}) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i == -1 {
+			break
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j == -1 {
+			break
+		}
+		name := s[i+2 : i+j]
+		v, ok := params[name]
+		if !ok {
+			return "", errors.New("soap: no parameter for template slot " + name)
+		}
+		text, err := p.slotText(name, v)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:i])
+		b.WriteString(text)
+		s = s[i+j+1:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+// slotText returns v as text of an element.
+func (p *Profile) slotText(name string, v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	m, ok := v.(*Element)
+	if !ok {
+		var err error
+		if m, err = p.Marshal(name, v); err != nil {
+			return "", err
+		}
+		defer m.Release()
+	}
+	if m.hasChildren() {
+		return "", errors.New("soap: template slot " + name + " needs a simple value")
+	}
+	return m.Text, nil
+}
@@ -0,0 +1,115 @@
+package soap
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unionMembers returns the member types of the union option or nil.
+func unionMembers(opts tagOptions) []string {
+	u := opts.Get("union")
+	if u == "" {
+		return nil
+	}
+	return strings.Split(u, "|")
+}
+
+// unionValue returns the value of e as the first of members that parses.
+// The type of e, if it is one of members, is tried first.
+func (e *Element) unionValue(members []string, o *loadOpts) (interface{}, error) {
+	if e.Nil {
+		return nil, nil
+	}
+	typ := skipNS(e.Type)
+	for i := -1; i < len(members); i++ {
+		m := typ
+		if i >= 0 {
+			if m = members[i]; m == typ {
+				continue
+			}
+		} else if !contains(members, m) {
+			continue
+		}
+		if !fitsInt(e.Text, m) {
+			continue
+		}
+		v, err := e.scalarValue(m, o.loc)
+		if err != nil || v == nil {
+			continue
+		}
+		if t, ok := v.(time.Time); ok && o.utc {
+			v = t.UTC()
+		}
+		return v, nil
+	}
+	return nil, e.badValue(strings.Join(members, "|"), nil)
+}
+
+// unionType changes the type of e, encoded from a Go value, to the first of
+// members of the same kind that can hold the value, if the type isn't one of
+// members.
+func (p *Profile) unionType(e *Element, members []string) {
+	typ := skipNS(e.Type)
+	if typ == "" || contains(members, typ) {
+		return
+	}
+	for _, m := range members {
+		if typeKind(m) == typeKind(typ) && fitsInt(e.Text, m) {
+			e.Type = p.xsdType(m)
+			return
+		}
+	}
+}
+
+// fitsInt reports whether s is in range of typ if typ is an integer type.
+func fitsInt(s, typ string) bool {
+	bits := intBits(typ)
+	if bits == 0 {
+		return true
+	}
+	var err error
+	if strings.HasPrefix(typ, "unsigned") {
+		_, err = strconv.ParseUint(s, 10, bits)
+	} else {
+		_, err = strconv.ParseInt(s, 10, bits)
+	}
+	return err == nil
+}
+
+// intBits returns the size of XML Schema integer type typ or 0.
+func intBits(typ string) int {
+	switch typ {
+	case "long", "unsignedLong":
+		return 64
+	case "int", "unsignedInt":
+		return 32
+	case "short", "unsignedShort":
+		return 16
+	case "byte", "unsignedByte":
+		return 8
+	}
+	return 0
+}
+
+// typeKind groups XML Schema types that Go values of one kind are encoded as.
+func typeKind(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "unsigned"):
+		return "unsigned"
+	case intBits(typ) != 0:
+		return "int"
+	case typ == "float" || typ == "double":
+		return "float"
+	}
+	return typ
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package soap
+
+import (
+	"strconv"
+)
+
+// ProfileUPnP describes UPnP devices: action arguments have no xsi:type
+// attributes and booleans are sent as 1 and 0.
+var ProfileUPnP = &Profile{OmitTypes: true, True: "1", False: "0"}
+
+// UPnPAction returns the value of SOAPACTION header for action of service
+// of given type, e.g. "urn:schemas-upnp-org:service:WANIPConnection:1".
+func UPnPAction(serviceType, action string) string {
+	return `"` + serviceType + "#" + action + `"`
+}
+
+// MakeUPnPRequest returns Body content for action of service of given type.
+// The fields of args struct (can be nil for actions without arguments) become
+// action arguments, encoded according to ProfileUPnP.
+func MakeUPnPRequest(serviceType, action string, args interface{}) *Element {
+	var e *Element
+	if args == nil {
+		e = new(Element)
+		e.XMLName.Local = action
+	} else {
+		e = ProfileUPnP.MakeElement(action, args)
+	}
+	e.XMLName.Space = serviceType
+	return e
+}
+
+// UPnPError is the fault detail returned by UPnP devices.
+type UPnPError struct {
+	Code        int    `xml:"errorCode"`
+	Description string `xml:"errorDescription"`
+}
+
+func (e *UPnPError) Error() string {
+	return "soap: UPnP error " + strconv.Itoa(e.Code) + ": " + e.Description
+}
+
+// UPnPFault can be used instead of Fault to decode faults returned by UPnP
+// devices, which carry the real error in UPnPError detail.
+type UPnPFault struct {
+	Code   string    `xml:"faultcode"`
+	String string    `xml:"faultstring"`
+	Detail UPnPError `xml:"detail>UPnPError"`
+}
+
+func (f *UPnPFault) Error() string {
+	return f.Detail.Error()
+}
@@ -0,0 +1,98 @@
+package soap
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// isUUIDType reports whether t is a UUID type: an array of 16 bytes, like
+// UUID types of popular packages (e.g. github.com/google/uuid). Values of
+// such types are written as xsd:string in the 8-4-4-4-12 form used by GUIDs
+// of .NET services instead of arrays.
+func isUUIDType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// setUUID sets type and text of e to UUID v (see isUUIDType).
+func (p *Profile) setUUID(e *Element, v reflect.Value) {
+	var u [16]byte
+	reflect.Copy(reflect.ValueOf(&u).Elem(), v)
+	e.Type = p.xsdType("string")
+	e.Text = formatUUID(u)
+}
+
+func formatUUID(u [16]byte) string {
+	b := make([]byte, 36)
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b)
+}
+
+var errBadUUID = errors.New("soap: UUID should have the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx")
+
+// parseUUID parses the 8-4-4-4-12 form of UUID, in either case and
+// optionally in braces.
+func parseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+	if len(s) == 38 && s[0] == '{' && s[37] == '}' {
+		s = s[1:37]
+	}
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, errBadUUID
+	}
+	h := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, errBadUUID
+	}
+	return u, nil
+}
+
+// UUID returns the value of e of xsd:string (or .NET guid) type as UUID.
+func (e *Element) UUID() ([16]byte, error) {
+	switch skipNS(e.Type) {
+	case "string", "guid":
+		return e.AsUUID()
+	}
+	return [16]byte{}, e.typeError("string")
+}
+
+// AsUUID parses the text of e as UUID regardless of the type of e.
+func (e *Element) AsUUID() ([16]byte, error) {
+	if e.hasChildren() {
+		return [16]byte{}, e.badValue("UUID", nil)
+	}
+	if e.Nil {
+		return [16]byte{}, nil
+	}
+	u, err := parseUUID(strings.TrimSpace(e.Text))
+	if err != nil {
+		return u, e.badValue("UUID", err)
+	}
+	return u, nil
+}
+
+// uuidLoader returns the loadFunc of fields of UUID type t.
+func uuidLoader(t reflect.Type) loadFunc {
+	return func(item *Element, v reflect.Value, o *loadOpts) error {
+		var (
+			u   [16]byte
+			err error
+		)
+		if o.mode == Strict {
+			u, err = item.UUID()
+		} else {
+			u, err = item.AsUUID()
+		}
+		reflect.Copy(v, reflect.ValueOf(u))
+		return err
+	}
+}
@@ -0,0 +1,217 @@
+package soap
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var errBadQName = errors.New("soap: malformed QName")
+
+// A Violation describes a place where an element tree doesn't conform to a
+// Schema.
+type Violation struct {
+	Path string // slash separated names from the root to the bad element
+	Msg  string
+}
+
+func (v Violation) Error() string {
+	return "soap: " + v.Path + ": " + v.Msg
+}
+
+// Validate checks e against the top level element of the same name in s and
+// returns all found violations (nil if e is valid). It checks everything
+// WriteTo writes: names, cardinality, lexical forms of built-in types,
+// enumerations and the length, pattern and digits facets. s is the model
+// built from Go types by Add; XSD documents aren't parsed.
+func (s *Schema) Validate(e *Element) []Violation {
+	e.Expand()
+	var vs []Violation
+	for _, d := range s.elems {
+		if d.name == e.XMLName.Local {
+			return d.validate(vs, e.XMLName.Local, e)
+		}
+	}
+	return append(vs, Violation{
+		e.XMLName.Local, "element isn't declared in schema",
+	})
+}
+
+func (d *schemaElem) validate(vs []Violation, path string, e *Element) []Violation {
+	if e.Nil {
+		if !d.nillable {
+			vs = append(vs, Violation{path, "element isn't nillable"})
+		}
+		return vs
+	}
+	if d.ct == nil {
+		if len(e.Children) != 0 {
+			return append(vs, Violation{path, "unexpected child elements"})
+		}
+		if err := checkLexical(d.xsdType, e.Text); err != "" {
+			return append(vs, Violation{path, err})
+		}
+		if d.enum != nil && !inList(d.enum, e.Text) {
+			return append(vs, Violation{
+				path,
+				"value '" + e.Text + "' isn't one of " +
+					strings.Join(d.enum, ", "),
+			})
+		}
+		if msg := d.checkFacets(e.Text); msg != "" {
+			return append(vs, Violation{path, msg})
+		}
+		return vs
+	}
+	count := make(map[string]int)
+	for _, c := range e.Children {
+		name := c.XMLName.Local
+		count[name]++
+		f := d.ct.field(name)
+		if f == nil {
+			vs = append(vs, Violation{
+				path + "/" + name, "element isn't declared in schema",
+			})
+			continue
+		}
+		if _, max := f.occurs(); max >= 0 && count[name] > max {
+			msg := "element occurs more than once"
+			if max != 1 {
+				msg = "element occurs more than " + strconv.Itoa(max) + " times"
+			}
+			vs = append(vs, Violation{path + "/" + name, msg})
+			continue
+		}
+		vs = f.validate(vs, path+"/"+name, c)
+	}
+	for _, f := range d.ct.fields {
+		min, _ := f.occurs()
+		switch n := count[f.name]; {
+		case n == 0 && min > 0:
+			vs = append(vs, Violation{
+				path + "/" + f.name, "required element is missing",
+			})
+		case n < min:
+			vs = append(vs, Violation{
+				path + "/" + f.name,
+				"element occurs less than " + strconv.Itoa(min) + " times",
+			})
+		}
+	}
+	return vs
+}
+
+func (ct *schemaType) field(name string) *schemaElem {
+	for _, f := range ct.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func inList(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFacets returns a description of the facet of d violated by s or "" if
+// s satisfies all of them.
+func (d *schemaElem) checkFacets(s string) string {
+	for _, f := range d.facets {
+		name := strings.TrimPrefix(f.XMLName.Local, "xsd:")
+		limit, _ := strconv.Atoi(f.Value)
+		switch name {
+		case "length", "minLength", "maxLength":
+			n, units := utf8.RuneCountInString(s), " characters"
+			if d.xsdType == "base64Binary" {
+				b, _ := base64.StdEncoding.DecodeString(stripSpace(s))
+				n, units = len(b), " bytes"
+			}
+			if name == "length" && n != limit ||
+				name == "minLength" && n < limit ||
+				name == "maxLength" && n > limit {
+				return "value of " + strconv.Itoa(n) + units + " violates " +
+					name + "=" + f.Value
+			}
+
+		case "pattern":
+			if !d.pattern.MatchString(s) {
+				return "value '" + s + "' doesn't match pattern " + f.Value
+			}
+
+		case "totalDigits", "fractionDigits":
+			df := digitsFacet{total: -1, fraction: -1}
+			if name == "totalDigits" {
+				df.total = limit
+			} else {
+				df.fraction = limit
+			}
+			if _, err := df.apply(s); err != nil {
+				return "value '" + s + "' violates " + name + "=" + f.Value
+			}
+		}
+	}
+	return ""
+}
+
+// checkLexical returns a description of the problem if s isn't a valid
+// literal of built-in XSD type typ.
+func checkLexical(typ, s string) string {
+	var err error
+	switch typ {
+	case "boolean":
+		switch s {
+		case "true", "false", "1", "0":
+		default:
+			return "bad boolean value '" + s + "'"
+		}
+	case "long":
+		_, err = strconv.ParseInt(s, 10, 64)
+	case "int":
+		_, err = strconv.ParseInt(s, 10, 32)
+	case "short":
+		_, err = strconv.ParseInt(s, 10, 16)
+	case "byte":
+		_, err = strconv.ParseInt(s, 10, 8)
+	case "unsignedLong":
+		_, err = strconv.ParseUint(s, 10, 64)
+	case "unsignedInt":
+		_, err = strconv.ParseUint(s, 10, 32)
+	case "unsignedShort":
+		_, err = strconv.ParseUint(s, 10, 16)
+	case "unsignedByte":
+		_, err = strconv.ParseUint(s, 10, 8)
+	case "float":
+		_, err = strconv.ParseFloat(s, 32)
+	case "double":
+		_, err = strconv.ParseFloat(s, 64)
+	case "dateTime":
+		_, err = parseDateTime(s, time.UTC)
+	case "decimal":
+		if !isDecimal(strings.TrimSpace(s)) {
+			err = errNotDecimal
+		}
+	case "base64Binary":
+		_, err = base64.StdEncoding.DecodeString(stripSpace(s))
+	case "QName":
+		s := strings.TrimSpace(s)
+		local := s[strings.IndexByte(s, ':')+1:]
+		if local == "" || strings.IndexByte(local, ':') != -1 || s[0] == ':' {
+			err = errBadQName
+		}
+	case "gYear", "gMonth", "gDay", "gMonthDay":
+		_, err = parseCalendar(typ, s)
+	}
+	if err != nil {
+		return "bad " + typ + " value '" + s + "'"
+	}
+	return ""
+}
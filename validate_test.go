@@ -0,0 +1,66 @@
+package soap
+
+import (
+	"strings"
+	"testing"
+)
+
+type validated struct {
+	Code   string  `soap:",length=3"`
+	Name   string  `soap:",minLength=2,maxLength=4"`
+	Sig    []byte  `soap:",maxLength=2"`
+	Amount float64 `soap:",totalDigits=4,fractionDigits=2"`
+	Year   GYear
+	SKU    string `soap:",pattern=[A-Z]+-[0-9]+"`
+}
+
+func TestValidateFacets(t *testing.T) {
+	s := NewSchema("")
+	if err := s.Add("V", validated{}); err != nil {
+		t.Fatal(err)
+	}
+	valid := map[string]string{
+		"Code": "abc", "Name": "ab", "Sig": "AQI=", "Amount": "12.5",
+		"Year": "2024", "SKU": "AB-1",
+	}
+	build := func(name, text string) *Element {
+		e := &Element{}
+		e.XMLName.Local = "V"
+		for _, f := range []string{"Code", "Name", "Sig", "Amount", "Year", "SKU"} {
+			c := &Element{Text: valid[f]}
+			c.XMLName.Local = f
+			if f == name {
+				c.Text = text
+			}
+			e.AddChild(c)
+		}
+		return e
+	}
+	if vs := s.Validate(build("", "")); vs != nil {
+		t.Fatalf("valid element: %v", vs)
+	}
+	cases := []struct{ name, text, msg string }{
+		{"Code", "abcd", "length=3"},
+		{"Name", "a", "minLength=2"},
+		{"Name", "abcde", "maxLength=4"},
+		{"Sig", "AQID", "value of 3 bytes violates maxLength=2"},
+		{"Sig", "A?", "bad base64Binary"},
+		{"Amount", "123.45", "totalDigits=4"},
+		{"Amount", "1.234", "fractionDigits=2"},
+		{"Amount", "1e3", "bad decimal"},
+		{"Year", "24", "bad gYear"},
+		{"SKU", "ab-1", "doesn't match pattern"},
+	}
+	for _, c := range cases {
+		vs := s.Validate(build(c.name, c.text))
+		if len(vs) != 1 || vs[0].Path != "V/"+c.name || !strings.Contains(vs[0].Msg, c.msg) {
+			t.Errorf("%s = %q: %v, want %s", c.name, c.text, vs, c.msg)
+		}
+	}
+	if msg := checkLexical("QName", "a:b:c"); msg == "" {
+		t.Error("QName a:b:c accepted")
+	}
+	if msg := checkLexical("QName", "p:x"); msg != "" {
+		t.Error(msg)
+	}
+}
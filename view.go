@@ -0,0 +1,200 @@
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+// setContent sets type, attributes and content of e to copies of those of
+// src. The name of e is kept.
+func (e *Element) setContent(src *Element) {
+	c := src.Clone()
+	e.Type, e.Nil, e.Text = c.Type, c.Nil, c.Text
+	e.Attrs, e.NS, e.Content, e.scope = c.Attrs, c.NS, c.Content, c.scope
+	e.SetChildren(c.Children)
+}
+
+// withType returns a shallow copy of e (sharing its children) with xsi:type
+// set to typ.
+func (e *Element) withType(typ string) *Element {
+	e.expand()
+	return &Element{
+		XMLName:  e.XMLName,
+		Type:     typ,
+		Nil:      e.Nil,
+		Attrs:    e.Attrs,
+		NS:       e.NS,
+		Text:     e.Text,
+		Children: e.Children,
+		Content:  e.Content,
+		scope:    e.scope,
+		stream:   e.stream,
+	}
+}
+
+// appendCopies appends copies of elements in src renamed to name to dst.
+func appendCopies(dst []*Element, name string, src []*Element) []*Element {
+	for _, s := range src {
+		if s == nil {
+			continue
+		}
+		c := s.Clone()
+		c.XMLName.Local = name
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// Clone returns a deep copy of the tree of e.
+func (e *Element) Clone() *Element {
+	type pair struct{ src, dst *Element }
+	root := newElement()
+	stack := []pair{{e, root}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		p.src.expand()
+		p.dst.XMLName = p.src.XMLName
+		p.dst.Type = p.src.Type
+		p.dst.Nil = p.src.Nil
+		p.dst.Text = p.src.Text
+		p.dst.Attrs = append(p.dst.Attrs[:0], p.src.Attrs...)
+		p.dst.NS = append(p.dst.NS[:0], p.src.NS...)
+		p.dst.scope = p.src.scope
+		p.dst.stream = p.src.stream
+		var clones map[*Element]*Element
+		if len(p.src.Content) != 0 {
+			clones = make(map[*Element]*Element)
+		}
+		for _, c := range p.src.Children {
+			if c == nil {
+				p.dst.Children = append(p.dst.Children, nil)
+				continue
+			}
+			dc := newElement()
+			p.dst.Children = append(p.dst.Children, dc)
+			stack = append(stack, pair{c, dc})
+			if clones != nil {
+				clones[c] = dc
+			}
+		}
+		for _, n := range p.src.Content {
+			if n.Elem != nil {
+				n.Elem = clones[n.Elem]
+			}
+			p.dst.Content = append(p.dst.Content, n)
+		}
+	}
+	return root
+}
+
+// A View is a read-only view of an Element tree. Views made by Freeze can be
+// cached and used by many goroutines at once, because nothing can modify the
+// underlying tree. The zero View represents a missing element.
+type View struct {
+	e *Element
+}
+
+// Freeze returns a View of a private copy of the tree of e, so later changes
+// of e don't affect it. Binary content of io.Reader fields, which can be
+// read only once, isn't copied.
+func (e *Element) Freeze() View {
+	c := e.Clone()
+	stack := []*Element{c}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		e.stream = nil
+		for _, c := range e.Children {
+			if c != nil {
+				stack = append(stack, c)
+			}
+		}
+	}
+	return View{c}
+}
+
+// Exists reports whether v represents an element (Get returns the zero View
+// for missing elements).
+func (v View) Exists() bool {
+	return v.e != nil
+}
+
+// Name returns the name of the element.
+func (v View) Name() xml.Name {
+	if v.e == nil {
+		return xml.Name{}
+	}
+	return v.e.XMLName
+}
+
+// Type returns the xsi:type of the element.
+func (v View) Type() string {
+	if v.e == nil {
+		return ""
+	}
+	return v.e.Type
+}
+
+// Nil reports whether the element has xsi:nil set.
+func (v View) Nil() bool {
+	return v.e != nil && v.e.Nil
+}
+
+// Text returns the text content of the element.
+func (v View) Text() string {
+	if v.e == nil {
+		return ""
+	}
+	return v.e.Text
+}
+
+// Len returns the number of children of the element.
+func (v View) Len() int {
+	if v.e == nil {
+		return 0
+	}
+	return len(v.e.Children)
+}
+
+// Child returns the i-th child of the element or the zero View if there is
+// no such child.
+func (v View) Child(i int) View {
+	if v.e == nil || i < 0 || i >= len(v.e.Children) {
+		return View{}
+	}
+	return View{v.e.Children[i]}
+}
+
+// Get works like Element.Get.
+func (v View) Get(key interface{}) (View, error) {
+	if v.e == nil {
+		return View{}, nil
+	}
+	c, err := v.e.Get(key)
+	return View{c}, err
+}
+
+// Value works like Element.Value.
+func (v View) Value() (interface{}, error) {
+	if v.e == nil {
+		return nil, nil
+	}
+	return v.e.Value()
+}
+
+// Load works like Element.Load.
+func (v View) Load(sp interface{}, mode DecodeMode) error {
+	if v.e == nil {
+		return errors.New("soap: can't load missing element")
+	}
+	return v.e.Load(sp, mode)
+}
+
+// Element returns a modifiable copy of the tree of v.
+func (v View) Element() *Element {
+	if v.e == nil {
+		return nil
+	}
+	return v.e.Clone()
+}
@@ -0,0 +1,34 @@
+package soap
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestViewChild(t *testing.T) {
+	var zero View
+	if zero.Child(0).Exists() {
+		t.Error("child of the zero View exists")
+	}
+	v := MakeElement("S", struct{ A, B int }{1, 2}).Freeze()
+	if v.Child(1).Text() != "2" {
+		t.Errorf("second child: %q", v.Child(1).Text())
+	}
+	if v.Child(2).Exists() || v.Child(-1).Exists() {
+		t.Error("child out of range exists")
+	}
+}
+
+func TestFreezeStream(t *testing.T) {
+	e := MakeElement("S", struct{ R io.Reader }{
+		strings.NewReader("data"),
+	})
+	v := e.Freeze()
+	if v.e.Children[0].stream != nil {
+		t.Error("frozen copy shares the stream")
+	}
+	if e.Children[0].stream == nil {
+		t.Error("stream of the original dropped")
+	}
+}
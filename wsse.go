@@ -0,0 +1,200 @@
+package soap
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Namespaces of WS-Security elements.
+const (
+	NamespaceWSSE = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	NamespaceWSU  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+)
+
+const (
+	passwordDigestType = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	base64EncodingType = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
+// Security is a WS-Security header with UsernameToken. Use Element to add
+// it to the header of an envelope written by Encoder, or marshal it with
+// encoding/xml.
+type Security struct {
+	XMLName       xml.Name      `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+	UsernameToken UsernameToken `xml:"UsernameToken"`
+}
+
+// UsernameToken is the token carried by Security header.
+type UsernameToken struct {
+	Username string   `xml:"Username"`
+	Password Password `xml:"Password"`
+	Nonce    Nonce    `xml:"Nonce"`
+	Created  string   `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+}
+
+// Password is the (possibly digested) password of UsernameToken.
+type Password struct {
+	Type string `xml:"Type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Nonce is the random nonce of UsernameToken.
+type Nonce struct {
+	EncodingType string `xml:"EncodingType,attr"`
+	Text         string `xml:",chardata"`
+}
+
+// NewDigestSecurity returns a Security header with UsernameToken using
+// password digest, as required by ONVIF devices. Devices reject tokens
+// created too far from their own clock, so skew (device time minus local
+// time, e.g. obtained from GetSystemDateAndTime) is added to the local time.
+func NewDigestSecurity(user, password string, skew time.Duration) (*Security, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	created := time.Now().Add(skew).UTC().Format("2006-01-02T15:04:05.000Z")
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+
+	s := new(Security)
+	s.UsernameToken.Username = user
+	s.UsernameToken.Password.Type = passwordDigestType
+	s.UsernameToken.Password.Text = base64.StdEncoding.EncodeToString(h.Sum(nil))
+	s.UsernameToken.Nonce.EncodingType = base64EncodingType
+	s.UsernameToken.Nonce.Text = base64.StdEncoding.EncodeToString(nonce)
+	s.UsernameToken.Created = created
+	return s, nil
+}
+
+// Element returns s as an element for the header of an envelope (see
+// Encoder.EncodeEnvelope).
+func (s *Security) Element() *Element {
+	wsse := func(local, text string) *Element {
+		e := newElement()
+		e.XMLName = xml.Name{Space: NamespaceWSSE, Local: local}
+		e.Text = text
+		return e
+	}
+	attr := func(e *Element, name, value string) {
+		e.Attrs = append(e.Attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+	}
+	t := &s.UsernameToken
+	pass := wsse("Password", t.Password.Text)
+	attr(pass, "Type", t.Password.Type)
+	nonce := wsse("Nonce", t.Nonce.Text)
+	attr(nonce, "EncodingType", t.Nonce.EncodingType)
+	created := newElement()
+	created.XMLName = xml.Name{Space: NamespaceWSU, Local: "Created"}
+	created.Text = t.Created
+
+	token := wsse("UsernameToken", "")
+	token.Children = append(token.Children, wsse("Username", t.Username), pass, nonce, created)
+	e := wsse("Security", "")
+	e.NS = append(e.NS,
+		xml.Attr{Name: xml.Name{Space: "xmlns", Local: "wsse"}, Value: NamespaceWSSE},
+		xml.Attr{Name: xml.Name{Space: "xmlns", Local: "wsu"}, Value: NamespaceWSU},
+	)
+	e.Children = append(e.Children, token)
+	return e
+}
+
+// ClockSkew returns the skew for NewDigestSecurity given the current time of
+// the device.
+func ClockSkew(deviceTime time.Time) time.Duration {
+	return deviceTime.Sub(time.Now())
+}
+
+// Errors returned by UsernameToken.Verify.
+var (
+	ErrBadPassword   = errors.New("soap: invalid UsernameToken password")
+	ErrStaleToken    = errors.New("soap: UsernameToken created outside of freshness window")
+	ErrReplayedNonce = errors.New("soap: replayed UsernameToken nonce")
+)
+
+// NonceStore remembers nonces of verified UsernameTokens to detect replays.
+// Implementations shared by many servers can keep them in a database.
+type NonceStore interface {
+	// Add records nonce until it expires. It returns false if nonce was
+	// already recorded and hasn't expired yet.
+	Add(nonce string, expires time.Time) bool
+}
+
+// MemNonceStore is a NonceStore that keeps nonces in memory. Expired nonces
+// are dropped as new ones are added. The zero value is ready to use.
+type MemNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	sweep  time.Time // when to drop expired nonces
+}
+
+// Add implements NonceStore.
+func (s *MemNonceStore) Add(nonce string, expires time.Time) bool {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nonces == nil {
+		s.nonces = make(map[string]time.Time)
+	}
+	if now.After(s.sweep) {
+		for n, exp := range s.nonces {
+			if now.After(exp) {
+				delete(s.nonces, n)
+			}
+		}
+		s.sweep = expires
+	}
+	if exp, ok := s.nonces[nonce]; ok && !now.After(exp) {
+		return false
+	}
+	s.nonces[nonce] = expires
+	return true
+}
+
+// Verify checks t received by a server against password of its user. Tokens
+// with password digest must be created within window of the local time and,
+// if store isn't nil, their nonces are recorded in it for twice the window
+// and tokens with recorded nonces are rejected, as required by the Basic
+// Security Profile. Tokens with plain text password are compared only.
+func (t *UsernameToken) Verify(password string, window time.Duration, store NonceStore) error {
+	if t.Password.Type != passwordDigestType {
+		if subtle.ConstantTimeCompare([]byte(t.Password.Text), []byte(password)) != 1 {
+			return ErrBadPassword
+		}
+		return nil
+	}
+	created, err := parseDateTime(t.Created, time.UTC)
+	if err != nil {
+		return ErrStaleToken
+	}
+	now := time.Now()
+	if created.Before(now.Add(-window)) || created.After(now.Add(window)) {
+		return ErrStaleToken
+	}
+	nonce, err := base64.StdEncoding.DecodeString(t.Nonce.Text)
+	if err != nil {
+		return ErrBadPassword
+	}
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(t.Created))
+	h.Write([]byte(password))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(t.Password.Text), []byte(digest)) != 1 {
+		return ErrBadPassword
+	}
+	// The nonce is recorded only for valid tokens, so forged ones can't
+	// block it.
+	if store != nil && !store.Add(t.Nonce.Text, created.Add(2*window)) {
+		return ErrReplayedNonce
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package soap
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityElement(t *testing.T) {
+	s, err := NewDigestSecurity("admin", "secret", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := MakeElement("GetTime", struct{}{})
+	out := new(Encoder).AppendEnvelope(nil, []*Element{s.Element()}, body)
+	if !strings.Contains(string(out), `<wsse:Password Type="`+passwordDigestType+`">`) {
+		t.Errorf("no typed password in %s", out)
+	}
+
+	var env struct {
+		Header struct {
+			Security Security
+		}
+	}
+	if err := xml.Unmarshal(out, &env); err != nil {
+		t.Fatal(err)
+	}
+	got := env.Header.Security
+	if got.UsernameToken != s.UsernameToken {
+		t.Fatalf("got %+v\nwant %+v", got.UsernameToken, s.UsernameToken)
+	}
+	store := new(MemNonceStore)
+	if err := got.UsernameToken.Verify("secret", time.Minute, store); err != nil {
+		t.Error(err)
+	}
+	if err := got.UsernameToken.Verify("secret", time.Minute, store); err != ErrReplayedNonce {
+		t.Errorf("replay: %v", err)
+	}
+	if err := got.UsernameToken.Verify("bad", time.Minute, nil); err != ErrBadPassword {
+		t.Errorf("bad password: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package soap
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// NamespaceXOP is the namespace of xop:Include elements, which refer to
+// MIME parts of XOP (MTOM) messages in place of base64 encoded content.
+const NamespaceXOP = "http://www.w3.org/2004/08/xop/include"
+
+// XOPInclude returns a new xop:Include element referring to the MIME part
+// of given Content-ID (without angle brackets). Set it as the only child of
+// an element to send its content as an attachment. The encoder writes it as
+// any other element, so the tree is never inlined.
+func XOPInclude(contentID string) *Element {
+	e := newElement()
+	e.XMLName = xml.Name{Space: NamespaceXOP, Local: "Include"}
+	e.Attrs = append(e.Attrs, xml.Attr{
+		Name:  xml.Name{Local: "href"},
+		Value: "cid:" + url.PathEscape(contentID),
+	})
+	return e
+}
+
+// ContentID returns the Content-ID of the MIME part referred by e, if e is
+// an xop:Include element or its only child is one.
+func (e *Element) ContentID() (string, bool) {
+	if e.XMLName.Space != NamespaceXOP || e.XMLName.Local != "Include" {
+		e.expand()
+		if len(e.Children) != 1 || e.Children[0] == nil {
+			return "", false
+		}
+		e = e.Children[0]
+		if e.XMLName.Space != NamespaceXOP || e.XMLName.Local != "Include" {
+			return "", false
+		}
+	}
+	for _, a := range e.Attrs {
+		if a.Name.Space != "" || a.Name.Local != "href" {
+			continue
+		}
+		href := strings.TrimSpace(a.Value)
+		if len(href) < 4 || !strings.EqualFold(href[:4], "cid:") {
+			return "", false
+		}
+		id, err := url.PathUnescape(href[4:])
+		if err != nil {
+			return "", false
+		}
+		return id, true
+	}
+	return "", false
+}
+
+// openPart returns the MIME part of given Content-ID, referred by item
+// loaded into a field of type typ, using o.parts (see Decoder.Parts).
+func openPart(item *Element, id, typ string, o *loadOpts) (io.Reader, error) {
+	if o.parts == nil {
+		return nil, item.badValue(
+			typ, errors.New("soap: unresolved xop:Include of cid:"+id),
+		)
+	}
+	r, err := o.parts(id)
+	if err != nil {
+		return nil, item.badValue(typ, err)
+	}
+	return r, nil
+}